@@ -0,0 +1,88 @@
+// Copyright 2015 Pikkpoiss
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"../lib/twodee"
+)
+
+type Achievement struct {
+	Title       string
+	Description string
+	Unlocked    bool
+	test        func(s *Stats) bool
+}
+
+func NewAchievements() []*Achievement {
+	return []*Achievement{
+		&Achievement{
+			Title:       "Boo!",
+			Description: "Scare your first visitor",
+			test:        func(s *Stats) bool { return s.Scares > 0 },
+		},
+		&Achievement{
+			Title:       "Interior Decorator",
+			Description: "Place 10 blocks",
+			test:        func(s *Stats) bool { return s.BlocksPlaced >= 10 },
+		},
+		&Achievement{
+			Title:       "Full House",
+			Description: "Send 100 visitors home",
+			test:        func(s *Stats) bool { return s.Exited >= 100 },
+		},
+		&Achievement{
+			Title:       "Heart Stopper",
+			Description: "Scare a visitor to death",
+			test:        func(s *Stats) bool { return s.Died > 0 },
+		},
+		&Achievement{
+			Title:       "Critically Acclaimed",
+			Description: "Reach a rating of 7",
+			test:        func(s *Stats) bool { return s.PeakRating >= 7 },
+		},
+		&Achievement{
+			Title:       "Loaded",
+			Description: "Earn 1000 geld",
+			test:        func(s *Stats) bool { return s.GeldEarned >= 1000 },
+		},
+	}
+}
+
+// AchievementTracker accumulates stats across every level played and
+// announces achievements as they are unlocked.
+type AchievementTracker struct {
+	*StatsTracker
+	Achievements []*Achievement
+	handler      *twodee.GameEventHandler
+}
+
+func NewAchievementTracker(handler *twodee.GameEventHandler) *AchievementTracker {
+	t := &AchievementTracker{
+		StatsTracker: NewStatsTracker(handler),
+		Achievements: NewAchievements(),
+		handler:      handler,
+	}
+	t.OnChange = t.check
+	return t
+}
+
+func (t *AchievementTracker) check(s *Stats) {
+	for _, achievement := range t.Achievements {
+		if !achievement.Unlocked && achievement.test(s) {
+			achievement.Unlocked = true
+			t.handler.Enqueue(NewAchievementEvent(achievement))
+		}
+	}
+}
@@ -16,148 +16,297 @@ package main
 
 import (
 	"../lib/twodee"
+	"github.com/go-gl/mathgl/mgl32"
+	"math"
+	"math/rand"
 	"time"
 )
 
 const (
-	MR_BONES_EFFECT_DURATION float64 = 2
-	SPIKES_EFFECT_DURATION   float64 = 2
+	MinSoundVolume    = 0.35 // Volume of a sound at the far edge of the view.
+	firstSoundChannel = 2
+	MusicDir          = "resources/music/"
 )
 
+// throttleKey identifies a catalog entry played from a single place so that
+// neighbouring traps don't silence each other.
+type throttleKey struct {
+	entry *SoundEntry
+	pos   Ivec2
+}
+
 type AudioSystem struct {
-	app                        *Application
-	bgm                        *twodee.Music
-	placeBlockEffect           *twodee.SoundEffect
-	mrbonesEffect              *twodee.SoundEffect
-	spikesEffect               *twodee.SoundEffect
-	deathEffect                *twodee.SoundEffect
-	bgmObserverId              int
-	pauseMusicObserverId       int
-	resumeMusicObserverId      int
-	placeBlockEffectObserverId int
-	mrbonesEffectObserverId    int
-	spikesEffectObserverId     int
-	deathEffectObserverId      int
-	musicToggle                int32
-	mrbonesLastPlayed          time.Time
-	spikesLastPlayed           time.Time
+	backend    AudioBackend
+	settings   *Settings
+	listener   func() twodee.Rectangle
+	playlist   *Playlist
+	music      map[string]Sound
+	tracks     []string
+	trackIndex int
+	level      string
+	mood       string
+	catalog    *SoundCatalog
+	effects    map[string]Sound
+	groups     map[string]*ChannelAllocator
+	subs       *Subscriptions
+	lastPlayed map[throttleKey]time.Time
 }
 
 func (a *AudioSystem) PlayBackgroundMusic(e twodee.GETyper) {
-	if a.musicToggle == 1 {
-		if twodee.MusicIsPlaying() {
-			twodee.PauseMusic()
-		}
-		a.bgm.Play(-1)
+	a.level = DefaultPlaylistLevel
+	if evt, ok := e.(*MusicEvent); ok {
+		a.level = evt.Level
 	}
+	a.mood = MoodCalm
+	a.switchTracks(true)
 }
 
 func (a *AudioSystem) PauseMusic(e twodee.GETyper) {
-	if a.musicToggle == 1 {
-		if twodee.MusicIsPlaying() {
-			twodee.PauseMusic()
-		}
+	if a.backend.MusicPlaying() {
+		a.backend.PauseMusic()
 	}
 }
 
 func (a *AudioSystem) ResumeMusic(e twodee.GETyper) {
-	if a.musicToggle == 1 {
-		if twodee.MusicIsPaused() {
-			twodee.ResumeMusic()
-		}
+	if !a.settings.MusicMuted && a.backend.MusicPaused() {
+		a.backend.ResumeMusic()
 	}
 }
 
-func (a *AudioSystem) PlayPlaceBlockEffect(e twodee.GETyper) {
-	if a.placeBlockEffect.IsPlaying(2) == 0 {
-		a.placeBlockEffect.PlayChannel(2, 1)
+// OnRatingChanged makes the music tense while the rating is about to drop
+// to a losing level.
+func (a *AudioSystem) OnRatingChanged(e twodee.GETyper) {
+	evt, ok := e.(*AmountEvent)
+	if !ok {
+		return
 	}
-}
-
-func (a *AudioSystem) PlayMrBonesEffect(e twodee.GETyper) {
-	if time.Since(a.mrbonesLastPlayed).Seconds() > MR_BONES_EFFECT_DURATION {
-		a.mrbonesEffect.PlayChannel(3, 1)
-		a.mrbonesLastPlayed = time.Now()
+	mood := MoodCalm
+	if evt.Value <= FAIL_RATING+1 {
+		mood = MoodTense
+	}
+	if mood != a.mood {
+		a.mood = mood
+		a.switchTracks(false)
 	}
 }
 
-func (a *AudioSystem) PlaySpikesEffect(e twodee.GETyper) {
-	if time.Since(a.spikesLastPlayed).Seconds() > SPIKES_EFFECT_DURATION {
-		a.spikesEffect.PlayChannel(4, 1)
-		a.spikesLastPlayed = time.Now()
+// switchTracks starts the tracks for the current level and mood. Unless
+// force is set, music carries on if the track list did not change.
+func (a *AudioSystem) switchTracks(force bool) {
+	var tracks = a.playlist.Tracks(a.level, a.mood)
+	if !force && sameTracks(tracks, a.tracks) {
+		return
 	}
+	a.tracks = tracks
+	a.trackIndex = 0
+	a.playTrack()
 }
 
-func (a *AudioSystem) PlayDeathEffect(e twodee.GETyper) {
-	if a.deathEffect.IsPlaying(5) == 0 {
-		a.deathEffect.PlayChannel(5, 1)
+func sameTracks(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
 	}
+	return true
 }
 
-func (a *AudioSystem) Delete() {
-	a.app.GameEventHandler.RemoveObserver(PlayBackgroundMusic, a.bgmObserverId)
-	a.app.GameEventHandler.RemoveObserver(PauseMusic, a.pauseMusicObserverId)
-	a.app.GameEventHandler.RemoveObserver(ResumeMusic, a.resumeMusicObserverId)
-	a.app.GameEventHandler.RemoveObserver(PlayPlaceBlockEffect, a.placeBlockEffectObserverId)
-	a.app.GameEventHandler.RemoveObserver(PlayMrBonesEffect, a.mrbonesEffectObserverId)
-	a.app.GameEventHandler.RemoveObserver(PlaySpikesEffect, a.spikesEffectObserverId)
-	a.app.GameEventHandler.RemoveObserver(PlayDeathEffect, a.deathEffectObserverId)
-	a.bgm.Delete()
-	a.placeBlockEffect.Delete()
-	a.mrbonesEffect.Delete()
-	a.spikesEffect.Delete()
-	a.deathEffect.Delete()
-}
-
-func NewAudioSystem(app *Application) (audioSystem *AudioSystem, err error) {
+func (a *AudioSystem) playTrack() {
 	var (
-		bgm              *twodee.Music
-		placeBlockEffect *twodee.SoundEffect
-		mrbonesEffect    *twodee.SoundEffect
-		spikesEffect     *twodee.SoundEffect
-		deathEffect      *twodee.SoundEffect
+		music Sound
+		ok    bool
+		err   error
+		path  string
 	)
-
-	if bgm, err = twodee.NewMusic("resources/music/bgm1.ogg"); err != nil {
+	if len(a.tracks) == 0 {
 		return
 	}
+	path = MusicDir + a.tracks[a.trackIndex%len(a.tracks)]
+	if music, ok = a.music[path]; !ok {
+		if music, err = a.backend.LoadMusic(path); err != nil {
+			return
+		}
+		a.music[path] = music
+	}
+	if a.backend.MusicPlaying() {
+		a.backend.PauseMusic()
+	}
+	a.backend.PlayMusic(music, 1)
+	if a.settings.MusicMuted {
+		a.backend.PauseMusic()
+	}
+}
 
-	if placeBlockEffect, err = twodee.NewSoundEffect("resources/music/place-block.ogg"); err != nil {
+// Update moves on to the next track in the playlist when one finishes.
+func (a *AudioSystem) Update(elapsed time.Duration) {
+	if a.settings.MusicMuted || len(a.tracks) == 0 {
 		return
 	}
+	if !a.backend.MusicPlaying() && !a.backend.MusicPaused() {
+		a.trackIndex = (a.trackIndex + 1) % len(a.tracks)
+		a.playTrack()
+	}
+}
 
-	if mrbonesEffect, err = twodee.NewSoundEffect("resources/music/deep-laugh.ogg"); err != nil {
-		return
+func (a *AudioSystem) Settings() *Settings {
+	return a.settings
+}
+
+// ApplySettings pushes changed volumes to the mixer and saves them.
+func (a *AudioSystem) ApplySettings() error {
+	a.backend.SetMusicVolume(a.settings.MusicLevel())
+	if a.settings.MusicMuted {
+		a.PauseMusic(nil)
+	} else {
+		a.ResumeMusic(nil)
 	}
+	return a.settings.Save()
+}
 
-	if spikesEffect, err = twodee.NewSoundEffect("resources/music/spikes.ogg"); err != nil {
-		return
+func (a *AudioSystem) ToggleMusic() error {
+	a.settings.MusicMuted = !a.settings.MusicMuted
+	return a.ApplySettings()
+}
+
+// PlayEventSounds plays whatever the sound catalog lists for e.
+func (a *AudioSystem) PlayEventSounds(e twodee.GETyper) {
+	for _, entry := range a.catalog.Sounds {
+		if entry.eventType == e.GEType() && entry.Matches(e) {
+			a.playEntry(entry, e)
+		}
 	}
+}
 
-	if deathEffect, err = twodee.NewSoundEffect("resources/music/no.ogg"); err != nil {
+// playEntry plays a random variant of entry unless it is cooling down.
+// Located events are panned and attenuated relative to the camera.
+func (a *AudioSystem) playEntry(entry *SoundEntry, e twodee.GETyper) {
+	var (
+		now         = time.Now()
+		volume      = a.settings.EffectLevel() * entry.Volume
+		key         = throttleKey{entry: entry}
+		left, right uint8
+		last        time.Time
+		channel     int
+		ok          bool
+	)
+	if volume <= 0 {
+		return
+	}
+	if sourced, isSourced := e.(BlockSourced); isSourced && entry.PerInstance {
+		key.pos = sourced.SourceBlock().Pos
+	}
+	if last, ok = a.lastPlayed[key]; ok && now.Sub(last).Seconds() < entry.Cooldown {
 		return
 	}
+	if channel, ok = a.groups[entry.Group].Allocate(entry.Priority, now); !ok {
+		return
+	}
+	left, right = 255, 255
+	if located, isLocated := e.(Located); isLocated {
+		left, right = StereoPosition(a.listener(), located.Location())
+	}
+	a.backend.PlayEffect(
+		a.effects[entry.Files[rand.Intn(len(entry.Files))]],
+		channel,
+		uint8(float64(left)*volume),
+		uint8(float64(right)*volume),
+	)
+	a.lastPlayed[key] = now
+}
 
-	mrbonesLastPlayed := time.Now()
-	spikesLastPlayed := time.Now()
+// StereoPosition returns left and right channel volumes for a sound at pos
+// heard from the middle of bounds. Sounds are panned toward the side they
+// are on and fade as they get further from the center of the view.
+func StereoPosition(bounds twodee.Rectangle, pos mgl32.Vec2) (left, right uint8) {
+	var (
+		halfSize = bounds.Max.Sub(bounds.Min.Vec2).Mul(0.5)
+		center   = bounds.Min.Add(halfSize)
+		offset   = pos.Sub(center)
+		pan      float64
+		dist     float64
+		volume   float64
+	)
+	if halfSize.X() > 0 {
+		pan = math.Max(-1, math.Min(1, float64(offset.X()/halfSize.X())))
+	}
+	if halfSize.Len() > 0 {
+		dist = math.Min(1, float64(offset.Len()/halfSize.Len()))
+	}
+	volume = 1 - (1-MinSoundVolume)*dist
+	left = uint8(255 * volume * math.Min(1, 1-pan))
+	right = uint8(255 * volume * math.Min(1, 1+pan))
+	return
+}
 
+func (a *AudioSystem) Delete() {
+	a.subs.Delete()
+	for _, music := range a.music {
+		music.Delete()
+	}
+	for _, effect := range a.effects {
+		effect.Delete()
+	}
+}
+
+// NewAudioSystem loads every sound in the catalog through backend. listener
+// should return the part of the world currently being looked at.
+func NewAudioSystem(handler *twodee.GameEventHandler, backend AudioBackend, settings *Settings, listener func() twodee.Rectangle) (audioSystem *AudioSystem, err error) {
+	var (
+		playlist *Playlist
+		catalog  *SoundCatalog
+		channel  = firstSoundChannel
+		effect   Sound
+		observed = map[twodee.GameEventType]bool{}
+	)
+	if playlist, err = LoadPlaylist(MusicDir + "playlist.json"); err != nil {
+		return
+	}
+	if catalog, err = LoadSoundCatalog(MusicDir + "sounds.json"); err != nil {
+		return
+	}
 	audioSystem = &AudioSystem{
-		app:               app,
-		bgm:               bgm,
-		placeBlockEffect:  placeBlockEffect,
-		mrbonesEffect:     mrbonesEffect,
-		spikesEffect:      spikesEffect,
-		deathEffect:       deathEffect,
-		musicToggle:       1,
-		mrbonesLastPlayed: mrbonesLastPlayed,
-		spikesLastPlayed:  spikesLastPlayed,
-	}
-	audioSystem.bgmObserverId = app.GameEventHandler.AddObserver(PlayBackgroundMusic, audioSystem.PlayBackgroundMusic)
-	audioSystem.pauseMusicObserverId = app.GameEventHandler.AddObserver(PauseMusic, audioSystem.PauseMusic)
-	audioSystem.resumeMusicObserverId = app.GameEventHandler.AddObserver(ResumeMusic, audioSystem.ResumeMusic)
-	audioSystem.placeBlockEffectObserverId = app.GameEventHandler.AddObserver(PlayPlaceBlockEffect, audioSystem.PlayPlaceBlockEffect)
-	audioSystem.mrbonesEffectObserverId = app.GameEventHandler.AddObserver(PlayMrBonesEffect, audioSystem.PlayMrBonesEffect)
-	audioSystem.spikesEffectObserverId = app.GameEventHandler.AddObserver(PlaySpikesEffect, audioSystem.PlaySpikesEffect)
-	audioSystem.deathEffectObserverId = app.GameEventHandler.AddObserver(PlayDeathEffect, audioSystem.PlayDeathEffect)
+		backend:    backend,
+		settings:   settings,
+		listener:   listener,
+		playlist:   playlist,
+		music:      map[string]Sound{},
+		level:      DefaultPlaylistLevel,
+		mood:       MoodCalm,
+		catalog:    catalog,
+		effects:    map[string]Sound{},
+		groups:     map[string]*ChannelAllocator{},
+		subs:       NewSubscriptions(handler),
+		lastPlayed: map[throttleKey]time.Time{},
+	}
+	for name, group := range catalog.Groups {
+		audioSystem.groups[name] = NewChannelAllocator(channel, group.Channels, backend.ChannelPlaying)
+		channel += group.Channels
+	}
+	backend.AllocateChannels(channel)
+	backend.SetMusicVolume(settings.MusicLevel())
+	for _, entry := range catalog.Sounds {
+		for _, file := range entry.Files {
+			if _, ok := audioSystem.effects[file]; ok {
+				continue
+			}
+			if effect, err = backend.LoadEffect(MusicDir + file); err != nil {
+				audioSystem.Delete()
+				audioSystem = nil
+				return
+			}
+			audioSystem.effects[file] = effect
+		}
+		if !observed[entry.eventType] {
+			observed[entry.eventType] = true
+			audioSystem.subs.Add(entry.eventType, audioSystem.PlayEventSounds)
+		}
+	}
+	audioSystem.subs.Add(PlayBackgroundMusic, audioSystem.PlayBackgroundMusic)
+	audioSystem.subs.Add(PauseMusic, audioSystem.PauseMusic)
+	audioSystem.subs.Add(ResumeMusic, audioSystem.ResumeMusic)
+	audioSystem.subs.Add(RatingChanged, audioSystem.OnRatingChanged)
 	return
 }
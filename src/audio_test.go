@@ -0,0 +1,175 @@
+// Copyright 2015 Pikkpoiss
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"../lib/twodee"
+	"fmt"
+	"github.com/go-gl/mathgl/mgl32"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+var listenerBounds = twodee.Rect(0, 0, 32, 20)
+
+func TestStereoPosition(t *testing.T) {
+	left, right := StereoPosition(listenerBounds, mgl32.Vec2{16, 10})
+	if left != 255 || right != 255 {
+		t.Fatalf("Expected full volume at center, got %v %v", left, right)
+	}
+	left, right = StereoPosition(listenerBounds, mgl32.Vec2{0, 10})
+	if right != 0 || left == 0 {
+		t.Fatalf("Expected sound on left only, got %v %v", left, right)
+	}
+	if left >= 255 {
+		t.Fatalf("Expected attenuation at edge of view, got %v", left)
+	}
+}
+
+func TestChannelAllocator(t *testing.T) {
+	var (
+		playing = map[int]bool{}
+		a       = NewChannelAllocator(2, 2, func(c int) bool { return playing[c] })
+		now     = time.Now()
+		c       int
+		ok      bool
+	)
+	if c, ok = a.Allocate(0, now); !ok || c != 2 {
+		t.Fatalf("Expected channel 2, got %v %v", c, ok)
+	}
+	playing[c] = true
+	if c, ok = a.Allocate(1, now.Add(time.Second)); !ok || c != 3 {
+		t.Fatalf("Expected channel 3, got %v %v", c, ok)
+	}
+	playing[c] = true
+	if c, ok = a.Allocate(0, now.Add(2*time.Second)); !ok || c != 2 {
+		t.Fatalf("Expected oldest trap channel 2 to be reused, got %v %v", c, ok)
+	}
+	a.Allocate(1, now.Add(3*time.Second))
+	if c, ok = a.Allocate(0, now.Add(4*time.Second)); ok {
+		t.Fatalf("Expected no channel for low priority sound, got %v", c)
+	}
+}
+
+func newTestAudioSystem(t *testing.T) (*AudioSystem, *NullAudioBackend) {
+	var (
+		backend = NewNullAudioBackend()
+		handler = twodee.NewGameEventHandler(NumGameEventTypes)
+		audio   *AudioSystem
+		err     error
+	)
+	audio, err = NewAudioSystem(handler, backend, NewSettings(), func() twodee.Rectangle {
+		return listenerBounds
+	})
+	if err != nil {
+		t.Fatalf("Could not create audio system: %v", err)
+	}
+	return audio, backend
+}
+
+type failingAudioBackend struct {
+	*NullAudioBackend
+}
+
+func (b failingAudioBackend) LoadEffect(path string) (Sound, error) {
+	return nil, fmt.Errorf("Could not load %v", path)
+}
+
+func TestAudioSystemFailsWithoutEffects(t *testing.T) {
+	var (
+		backend = failingAudioBackend{NewNullAudioBackend()}
+		handler = twodee.NewGameEventHandler(NumGameEventTypes)
+	)
+	audio, err := NewAudioSystem(handler, backend, NewSettings(), func() twodee.Rectangle {
+		return listenerBounds
+	})
+	if err == nil || audio != nil {
+		t.Fatalf("Expected no audio system when effects fail to load, got %v %v", audio, err)
+	}
+}
+
+func TestAudioSystemVolumes(t *testing.T) {
+	audio, backend := newTestAudioSystem(t)
+	defer audio.Delete()
+	audio.PlayBackgroundMusic(NewMusicEvent(DefaultPlaylistLevel))
+	if !backend.MusicPlaying() {
+		t.Fatalf("Expected music to be playing")
+	}
+	audio.Settings().MusicMuted = true
+	audio.ApplySettings()
+	if backend.MusicPlaying() || backend.MusicVolume != 0 {
+		t.Fatalf("Expected muted music, volume %v", backend.MusicVolume)
+	}
+
+	audio.Settings().EffectVolume = 0.5
+	audio.PlayEventSounds(&MobEvent{
+		BasicGameEvent: twodee.NewBasicGameEvent(MobDied),
+		Pos:            mgl32.Vec2{16, 10},
+	})
+	if len(backend.Played) != 1 {
+		t.Fatalf("Expected one effect played, got %v", len(backend.Played))
+	}
+	if played := backend.Played[0]; played.Left != 127 || played.Right != 127 {
+		t.Fatalf("Expected half volume, got %v %v", played.Left, played.Right)
+	}
+
+	audio.Settings().EffectVolume = 0
+	audio.PlayEventSounds(&MobEvent{
+		BasicGameEvent: twodee.NewBasicGameEvent(MobDied),
+	})
+	if len(backend.Played) != 1 {
+		t.Fatalf("Expected silent effects to be skipped")
+	}
+}
+
+func TestMusicFollowsMood(t *testing.T) {
+	audio, backend := newTestAudioSystem(t)
+	defer audio.Delete()
+	audio.PlayBackgroundMusic(NewMusicEvent("Opening Night"))
+	calm := backend.Music
+	if calm == "" {
+		t.Fatalf("Expected the default tracks for an unlisted level")
+	}
+	audio.OnRatingChanged(NewAmountEvent(RatingChanged, FAIL_RATING+1, -1))
+	if backend.Music == calm {
+		t.Fatalf("Expected a different track when tense, still playing %v", calm)
+	}
+	audio.OnRatingChanged(NewAmountEvent(RatingChanged, WIN_RATING, 1))
+	if backend.Music != calm {
+		t.Fatalf("Expected %v once calm again, got %v", calm, backend.Music)
+	}
+}
+
+func TestLoadSettingsFallsBack(t *testing.T) {
+	var (
+		file *os.File
+		s    *Settings
+		err  error
+	)
+	if file, err = ioutil.TempFile("", "settings"); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	file.WriteString(`{"MasterVolume": 0.2, "MusicVolume": `)
+	file.Close()
+	if s, err = LoadSettings(file.Name()); err == nil {
+		t.Fatalf("Expected an error for malformed settings")
+	}
+	if s.MasterVolume != NewSettings().MasterVolume {
+		t.Errorf("Expected default volume, got %v", s.MasterVolume)
+	}
+}
@@ -0,0 +1,176 @@
+// Copyright 2015 Pikkpoiss
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"../lib/twodee"
+	mix "github.com/veandco/go-sdl2/sdl_mixer"
+	"math"
+)
+
+// Sound is a loaded sound effect or music track.
+type Sound interface {
+	Delete()
+}
+
+// AudioBackend is everything AudioSystem needs from the mixer.
+type AudioBackend interface {
+	LoadEffect(path string) (Sound, error)
+	LoadMusic(path string) (Sound, error)
+	AllocateChannels(count int)
+	PlayEffect(effect Sound, channel int, left, right uint8)
+	ChannelPlaying(channel int) bool
+	PlayMusic(music Sound, loops int)
+	PauseMusic()
+	ResumeMusic()
+	MusicPlaying() bool
+	MusicPaused() bool
+	SetMusicVolume(volume float64)
+}
+
+// SdlAudioBackend plays audio through twodee and SDL_mixer.
+type SdlAudioBackend struct{}
+
+func NewSdlAudioBackend() *SdlAudioBackend {
+	return &SdlAudioBackend{}
+}
+
+func (b *SdlAudioBackend) LoadEffect(path string) (sound Sound, err error) {
+	var effect *twodee.SoundEffect
+	if effect, err = twodee.NewSoundEffect(path); err != nil {
+		return
+	}
+	return effect, nil
+}
+
+func (b *SdlAudioBackend) LoadMusic(path string) (sound Sound, err error) {
+	var music *twodee.Music
+	if music, err = twodee.NewMusic(path); err != nil {
+		return
+	}
+	return music, nil
+}
+
+func (b *SdlAudioBackend) AllocateChannels(count int) {
+	mix.AllocateChannels(count)
+}
+
+func (b *SdlAudioBackend) PlayEffect(effect Sound, channel int, left, right uint8) {
+	mix.SetPanning(channel, left, right)
+	effect.(*twodee.SoundEffect).PlayChannel(channel, 1)
+}
+
+func (b *SdlAudioBackend) ChannelPlaying(channel int) bool {
+	return mix.Playing(channel) != 0
+}
+
+func (b *SdlAudioBackend) PlayMusic(music Sound, loops int) {
+	music.(*twodee.Music).Play(loops)
+}
+
+func (b *SdlAudioBackend) PauseMusic() {
+	twodee.PauseMusic()
+}
+
+func (b *SdlAudioBackend) ResumeMusic() {
+	twodee.ResumeMusic()
+}
+
+func (b *SdlAudioBackend) MusicPlaying() bool {
+	return twodee.MusicIsPlaying()
+}
+
+func (b *SdlAudioBackend) MusicPaused() bool {
+	return twodee.MusicIsPaused()
+}
+
+func (b *SdlAudioBackend) SetMusicVolume(volume float64) {
+	mix.VolumeMusic(int(math.Floor(volume*mix.MAX_VOLUME + 0.5)))
+}
+
+type nullSound struct {
+	Path string
+}
+
+func (s *nullSound) Delete() {
+}
+
+// PlayedEffect records a call to NullAudioBackend.PlayEffect.
+type PlayedEffect struct {
+	Path        string
+	Channel     int
+	Left, Right uint8
+}
+
+// NullAudioBackend makes no noise and just remembers what it was asked to
+// do, so audio can run headless.
+type NullAudioBackend struct {
+	Played      []PlayedEffect
+	Playing     map[int]bool
+	Music       string
+	Paused      bool
+	MusicVolume float64
+}
+
+func NewNullAudioBackend() *NullAudioBackend {
+	return &NullAudioBackend{
+		Playing: map[int]bool{},
+	}
+}
+
+func (b *NullAudioBackend) LoadEffect(path string) (Sound, error) {
+	return &nullSound{path}, nil
+}
+
+func (b *NullAudioBackend) LoadMusic(path string) (Sound, error) {
+	return &nullSound{path}, nil
+}
+
+func (b *NullAudioBackend) AllocateChannels(count int) {
+}
+
+func (b *NullAudioBackend) PlayEffect(effect Sound, channel int, left, right uint8) {
+	b.Played = append(b.Played, PlayedEffect{effect.(*nullSound).Path, channel, left, right})
+	b.Playing[channel] = true
+}
+
+func (b *NullAudioBackend) ChannelPlaying(channel int) bool {
+	return b.Playing[channel]
+}
+
+func (b *NullAudioBackend) PlayMusic(music Sound, loops int) {
+	b.Music = music.(*nullSound).Path
+	b.Paused = false
+}
+
+func (b *NullAudioBackend) PauseMusic() {
+	b.Paused = true
+}
+
+func (b *NullAudioBackend) ResumeMusic() {
+	b.Paused = false
+}
+
+func (b *NullAudioBackend) MusicPlaying() bool {
+	return b.Music != "" && !b.Paused
+}
+
+func (b *NullAudioBackend) MusicPaused() bool {
+	return b.Music != "" && b.Paused
+}
+
+func (b *NullAudioBackend) SetMusicVolume(volume float64) {
+	b.MusicVolume = volume
+}
@@ -0,0 +1,189 @@
+// Copyright 2015 Pikkpoiss
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"../lib/twodee"
+	"github.com/go-gl/mathgl/mgl32"
+	"math"
+	"time"
+)
+
+const (
+	AvatarSpeed        = 3.0
+	AvatarMaxEnergy    = 100.0
+	AvatarEnergyPerSec = 5.0
+)
+
+// Ability is something the avatar can do at the cost of some energy.
+type Ability struct {
+	Name     string
+	Energy   float64
+	Cooldown time.Duration
+	Range    float32
+	Fear     float64       // Added at once to every mob in range.
+	Duration time.Duration // How long lasting effects last.
+	Speed    float64       // Multiplier for the speed of mobs in range.
+	Action   InputAction
+}
+
+var (
+	BooAbility = Ability{
+		Name:     "Boo!",
+		Energy:   30,
+		Cooldown: 3 * time.Second,
+		Range:    3.0,
+		Fear:     3.0,
+		Action:   ActionBoo,
+	}
+
+	ChillAbility = Ability{
+		Name:     "Chill",
+		Energy:   40,
+		Cooldown: 10 * time.Second,
+		Range:    3.0,
+		Duration: 5 * time.Second,
+		Speed:    0.4,
+		Action:   ActionChill,
+	}
+
+	PhaseAbility = Ability{
+		Name:     "Phase",
+		Energy:   50,
+		Cooldown: 8 * time.Second,
+		Range:    6.0,
+		Action:   ActionPhase,
+	}
+
+	Abilities = []*Ability{
+		&BooAbility,
+		&ChillAbility,
+		&PhaseAbility,
+	}
+)
+
+// Avatar is the monster the player controls directly.
+type Avatar struct {
+	Pos       mgl32.Vec2
+	Energy    float64
+	dir       mgl32.Vec2
+	held      map[mgl32.Vec2]bool // Directions of the keys being held.
+	dest      mgl32.Vec2
+	moving    bool
+	left      bool
+	chillFor  time.Duration
+	cooldowns map[*Ability]time.Duration
+}
+
+func NewAvatar(pos mgl32.Vec2) *Avatar {
+	return &Avatar{
+		Pos:       pos,
+		Energy:    AvatarMaxEnergy,
+		held:      map[mgl32.Vec2]bool{},
+		cooldowns: map[*Ability]time.Duration{},
+	}
+}
+
+// Steer adds or removes a direction the avatar is being pushed in by keys.
+// Key repeats and releases without a press don't change anything.
+func (a *Avatar) Steer(dir mgl32.Vec2, held bool) {
+	if held {
+		a.held[dir] = true
+	} else {
+		delete(a.held, dir)
+	}
+	a.dir = mgl32.Vec2{}
+	for d := range a.held {
+		a.dir = a.dir.Add(d)
+	}
+	a.moving = false
+}
+
+// MoveTo sends the avatar walking in a straight line towards pos.
+func (a *Avatar) MoveTo(pos mgl32.Vec2) {
+	a.dest = pos
+	a.moving = true
+}
+
+// Cooldown returns how long until ability can be used again.
+func (a *Avatar) Cooldown(ability *Ability) time.Duration {
+	return a.cooldowns[ability]
+}
+
+// Ready returns true if the avatar can use ability now.
+func (a *Avatar) Ready(ability *Ability) bool {
+	return a.cooldowns[ability] <= 0 && a.Energy >= ability.Energy
+}
+
+// use pays for ability and starts its cooldown.
+func (a *Avatar) use(ability *Ability) {
+	a.Energy -= ability.Energy
+	a.cooldowns[ability] = ability.Cooldown
+}
+
+// Chilling returns the speed multiplier for mobs at pos due to the avatar's
+// chill, or 1 if they are unaffected.
+func (a *Avatar) Chilling(pos mgl32.Vec2) float64 {
+	if a.chillFor <= 0 || pos.Sub(a.Pos).Len() > ChillAbility.Range {
+		return 1
+	}
+	return ChillAbility.Speed
+}
+
+func (a *Avatar) Update(elapsed time.Duration, grid *Grid) {
+	a.Energy = math.Min(AvatarMaxEnergy, a.Energy+AvatarEnergyPerSec*elapsed.Seconds())
+	for ability, left := range a.cooldowns {
+		a.cooldowns[ability] = left - elapsed
+	}
+	a.chillFor -= elapsed
+	var (
+		step = float32(elapsed.Seconds()) * AvatarSpeed
+		dir  = a.dir
+	)
+	if a.moving {
+		if dir = a.dest.Sub(a.Pos); dir.Len() <= step {
+			a.moving = false
+			step = dir.Len()
+		}
+	}
+	if dir.Len() == 0 {
+		return
+	}
+	next := a.Pos.Add(dir.Normalize().Mul(step))
+	if !grid.isWalkable(grid.WorldToGrid(next)) {
+		a.moving = false
+		return
+	}
+	a.left = dir.X() < 0
+	a.Pos = next
+}
+
+func (a *Avatar) SpriteConfig(sheet *twodee.Spritesheet) twodee.SpriteConfig {
+	var (
+		frame          = sheet.GetFrame("ghost01_00")
+		scaleX float32 = 1.5
+	)
+	if a.left {
+		scaleX = -scaleX
+	}
+	return twodee.SpriteConfig{
+		View: twodee.ModelViewConfig{
+			a.Pos.X(), a.Pos.Y() + frame.Height/2.0, 0.0,
+			0, 0, 0,
+			scaleX, 1.5, 1.0,
+		},
+		Frame: frame.Frame,
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright 2015 Pikkpoiss
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"testing"
+)
+
+func TestSteerKeepsHeldKeys(t *testing.T) {
+	var (
+		avatar = NewAvatar(mgl32.Vec2{})
+		up     = mgl32.Vec2{0, 1}
+		right  = mgl32.Vec2{1, 0}
+	)
+	for _, st := range []struct {
+		dir      mgl32.Vec2
+		held     bool
+		expected mgl32.Vec2
+	}{
+		{up, true, up},
+		{up, true, up}, // Key repeat.
+		{right, true, up.Add(right)},
+		{up, false, right},
+		{up, false, right}, // Release without a press.
+		{right, false, mgl32.Vec2{}},
+	} {
+		avatar.Steer(st.dir, st.held)
+		if avatar.dir != st.expected {
+			t.Errorf("Expected steering %v held %v to leave direction %v, got %v", st.dir, st.held, st.expected, avatar.dir)
+		}
+	}
+}
+
+func TestAbilitiesCostEnergyAndCool(t *testing.T) {
+	var (
+		grid   = newTestGrid(4, 4)
+		avatar = NewAvatar(mgl32.Vec2{1.5, 1.5})
+	)
+	if !avatar.Ready(&BooAbility) {
+		t.Fatalf("Expected a new avatar to be ready")
+	}
+	avatar.use(&BooAbility)
+	if avatar.Energy != AvatarMaxEnergy-BooAbility.Energy {
+		t.Errorf("Expected using %v to cost %v energy, left %v", BooAbility.Name, BooAbility.Energy, avatar.Energy)
+	}
+	if avatar.Ready(&BooAbility) {
+		t.Errorf("Expected %v to be cooling down", BooAbility.Name)
+	}
+	if !avatar.Ready(&ChillAbility) {
+		t.Errorf("Expected other abilities to stay ready")
+	}
+	avatar.Update(BooAbility.Cooldown, grid)
+	if !avatar.Ready(&BooAbility) {
+		t.Errorf("Expected %v to be ready after cooling down", BooAbility.Name)
+	}
+	avatar.Energy = BooAbility.Energy - 1
+	if avatar.Ready(&BooAbility) {
+		t.Errorf("Expected %v to need %v energy", BooAbility.Name, BooAbility.Energy)
+	}
+}
+
+func TestChilling(t *testing.T) {
+	var (
+		grid   = newTestGrid(4, 4)
+		avatar = NewAvatar(mgl32.Vec2{1.5, 1.5})
+		near   = mgl32.Vec2{2.5, 1.5}
+		far    = avatar.Pos.Add(mgl32.Vec2{ChillAbility.Range + 1, 0})
+	)
+	if speed := avatar.Chilling(near); speed != 1 {
+		t.Errorf("Expected no chill before the ability is used, got %v", speed)
+	}
+	avatar.chillFor = ChillAbility.Duration
+	if speed := avatar.Chilling(near); speed != ChillAbility.Speed {
+		t.Errorf("Expected mobs in range to be slowed to %v, got %v", ChillAbility.Speed, speed)
+	}
+	if speed := avatar.Chilling(far); speed != 1 {
+		t.Errorf("Expected mobs out of range not to be slowed, got %v", speed)
+	}
+	avatar.Update(ChillAbility.Duration, grid)
+	if speed := avatar.Chilling(near); speed != 1 {
+		t.Errorf("Expected the chill to wear off, got %v", speed)
+	}
+}
+
+func TestPhaseRange(t *testing.T) {
+	var (
+		grid  = newTestGrid(12, 3)
+		level *Level
+	)
+	grid.floors = []Floor{{Name: "test", Width: 12, Height: 3}}
+	grid.Set(Ivec2{4, 1}, NewGridItem(false, true, "", nil))
+	level = newTestLevel(grid)
+	level.Avatar.Pos = mgl32.Vec2{1.5, 1.5}
+	for _, pt := range []struct {
+		target mgl32.Vec2
+		ok     bool
+	}{
+		{mgl32.Vec2{1.5 + PhaseAbility.Range + 1, 1.5}, false}, // Too far.
+		{mgl32.Vec2{4.5, 1.5}, false},                          // Inside a wall.
+		{mgl32.Vec2{5.5, 1.5}, true},                           // Through the wall.
+	} {
+		level.State.MousePos = pt.target
+		energy := level.Avatar.Energy
+		if ok := level.UseAbility(&PhaseAbility); ok != pt.ok {
+			t.Errorf("Expected phasing to %v to be %v", pt.target, pt.ok)
+		}
+		if moved := level.Avatar.Pos == pt.target; moved != pt.ok {
+			t.Errorf("Expected phasing to %v to move the avatar %v, at %v", pt.target, pt.ok, level.Avatar.Pos)
+		}
+		if paid := level.Avatar.Energy < energy; paid != pt.ok {
+			t.Errorf("Expected phasing to %v to cost energy %v", pt.target, pt.ok)
+		}
+	}
+}
@@ -14,7 +14,10 @@
 
 package main
 
-import ()
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"time"
+)
 
 type BlockState int32
 
@@ -22,12 +25,26 @@ const (
 	_                      = iota
 	BlockNormal BlockState = 1 << iota
 	BlockScaring
+	BlockOpen
+	BlockDisabled
 )
 
 type BlockPlacement struct {
-	Pos     Ivec2
-	Block   *Block
-	Variant int
+	Pos         Ivec2
+	Block       *Block
+	Variant     int
+	Health      float64
+	DisabledFor time.Duration // Time left until a disabled block works again.
+}
+
+// Broken returns true if the placement has run out of health.
+func (p BlockPlacement) Broken() bool {
+	return p.Block.Health > 0 && p.Health <= 0
+}
+
+// Active returns true if the placement is neither broken nor disabled.
+func (p BlockPlacement) Active() bool {
+	return !p.Broken() && p.DisabledFor <= 0
 }
 
 func (p BlockPlacement) Intersects(gridCoords Ivec2) bool {
@@ -44,14 +61,22 @@ func (p BlockPlacement) Intersects(gridCoords Ivec2) bool {
 	return false
 }
 
+// Center returns the world coordinates of the middle of the placement's
+// origin tile.
+func (p BlockPlacement) Center() mgl32.Vec2 {
+	return mgl32.Vec2{float32(p.Pos.X()), float32(p.Pos.Y())}.Add(mgl32.Vec2{0.5, 0.5})
+}
+
 type BlockAnimations map[BlockState][]int
 
 var (
 	SkeletonAnimations = BlockAnimations{
-		BlockNormal:  []int{0},
-		BlockScaring: []int{1, 2, 3, 4},
+		BlockNormal:   []int{0},
+		BlockScaring:  []int{1, 2, 3, 4},
+		BlockDisabled: []int{5},
 	}
 	SkeletonTemplate = &GridItemTemplate{
+		false,
 		false,
 		"skeleton01_%02v",
 		SkeletonAnimations,
@@ -68,9 +93,11 @@ var (
 			4, 4,
 			0, 0, 0, 0, 0, 0, 0, 0,
 		},
+		BlockDisabled: []int{5},
 	}
 	SpikesTemplate = &GridItemTemplate{
 		false,
+		true,
 		"spikes01_%02v",
 		SpikesAnimations,
 	}
@@ -78,18 +105,56 @@ var (
 
 var (
 	BoxAnimations = BlockAnimations{
-		BlockNormal:  []int{0},
-		BlockScaring: []int{0},
+		BlockNormal:   []int{0},
+		BlockScaring:  []int{0},
+		BlockDisabled: []int{1},
 	}
 	BoxTemplate = &GridItemTemplate{
 		false,
+		true,
 		"box01_%02v",
 		BoxAnimations,
 	}
 )
 
+var (
+	LureTemplate = &GridItemTemplate{
+		false,
+		true,
+		"lure01_%02v",
+		BoxAnimations,
+	}
+	ShadowTemplate = &GridItemTemplate{
+		false,
+		false,
+		"shadow01_%02v",
+		BoxAnimations,
+	}
+)
+
+var (
+	DoorAnimations = BlockAnimations{
+		BlockNormal: []int{0},
+		BlockOpen:   []int{1},
+	}
+	DoorTemplate = &GridItemTemplate{
+		false,
+		true,
+		"gate_%02v",
+		DoorAnimations,
+	}
+	// Fills the rest of the door, which is drawn by DoorTemplate.
+	DoorFrameTemplate = &GridItemTemplate{
+		false,
+		true,
+		"",
+		nil,
+	}
+)
+
 type GridItemTemplate struct {
 	Passable bool
+	Opaque   bool // Blocks line of sight.
 	Frame    string
 	Frames   BlockAnimations
 }
@@ -103,11 +168,19 @@ type Block struct {
 	Range        float32 // Radius of effectiveness.
 	MaxTargets   int     // -1 for infinite.
 	FearPerSec   float64 // Amount of fear added to target per second.
+	Speed        float64 // Speed multiplier for mobs within Range, 0 for none.
+	PathCost     float64 // Route cost multiplier within Range, below 1 lures and above 1 repels.
+	Theme        string  // Rooms full of blocks with one theme are scarier.
+	Door         bool    // Opened and closed by clicking on it.
+	Attraction   float64 // Chance of a visitor going to see this block.
+	Health       float64 // 0 for blocks which can't be damaged.
+	Wear         float64 // Health lost per second spent scaring.
+	Upkeep       int     // Geld charged every UpkeepEvery.
 	Cost         int
 	Title        string
 	IconEnabled  string
 	IconDisabled string
-	Key          string
+	Action       InputAction // Selects this block from the toolbar.
 }
 
 var (
@@ -125,9 +198,14 @@ var (
 		FearPerSec:   2.0,
 		Cost:         10,
 		Title:        "Mr. Bones",
+		Theme:        "crypt",
+		Attraction:   0.5,
+		Health:       10,
+		Wear:         0.1,
+		Upkeep:       1,
 		IconEnabled:  "icons_00",
 		IconDisabled: "icons_desaturated_00",
-		Key:          "1",
+		Action:       ActionBlock1,
 	}
 
 	SpikesBlock = Block{
@@ -149,9 +227,13 @@ var (
 		FearPerSec:   0.5,
 		Cost:         100,
 		Title:        "Spiketron 5000",
+		Theme:        "dungeon",
+		Health:       20,
+		Wear:         0.2,
+		Upkeep:       5,
 		IconEnabled:  "icons_01",
 		IconDisabled: "icons_desaturated_01",
-		Key:          "2",
+		Action:       ActionBlock2,
 	}
 
 	CornerBlock = Block{
@@ -181,11 +263,16 @@ var (
 		Range:        5.0,
 		MaxTargets:   3,
 		FearPerSec:   0.5,
+		Speed:        0.75,
 		Cost:         100,
 		Title:        "Spiketron 6000 GT",
+		Theme:        "dungeon",
+		Health:       20,
+		Wear:         0.2,
+		Upkeep:       5,
 		IconEnabled:  "icons_02",
 		IconDisabled: "icons_desaturated_02",
-		Key:          "3",
+		Action:       ActionBlock3,
 	}
 
 	ScaryBox = Block{
@@ -200,9 +287,64 @@ var (
 		FearPerSec:   -2.0,
 		Cost:         50,
 		Title:        "Unscary Box",
+		Theme:        "attic",
+		Health:       10,
 		IconEnabled:  "icons_03",
 		IconDisabled: "icons_desaturated_03",
-		Key:          "4",
+		Action:       ActionBlock4,
+	}
+
+	DoorBlock = Block{
+		Variants: []BlockTemplate{
+			BlockTemplate{
+				[]*GridItemTemplate{DoorTemplate, DoorFrameTemplate, DoorFrameTemplate},
+			},
+		},
+		Offset:       Ivec2{-1, 0},
+		Cost:         25,
+		Title:        "Creaky Door",
+		Door:         true,
+		IconEnabled:  "gate_01",
+		IconDisabled: "gate_00",
+		Action:       ActionBlock5,
+	}
+
+	LureBlock = Block{
+		Variants: []BlockTemplate{
+			BlockTemplate{
+				[]*GridItemTemplate{LureTemplate},
+			},
+		},
+		Offset:       Ivec2{0, 0},
+		Range:        4.0,
+		PathCost:     0.3,
+		Cost:         40,
+		Title:        "Curious Noise",
+		Health:       10,
+		IconEnabled:  "icons_05",
+		IconDisabled: "icons_desaturated_05",
+		Action:       ActionBlock6,
+	}
+
+	ShadowBlock = Block{
+		Variants: []BlockTemplate{
+			BlockTemplate{
+				[]*GridItemTemplate{ShadowTemplate},
+			},
+		},
+		Offset:       Ivec2{0, 0},
+		Range:        3.0,
+		MaxTargets:   2,
+		FearPerSec:   0.3,
+		PathCost:     4.0,
+		Cost:         60,
+		Title:        "Menacing Shadow",
+		Theme:        "crypt",
+		Health:       10,
+		Wear:         0.05,
+		IconEnabled:  "icons_06",
+		IconDisabled: "icons_desaturated_06",
+		Action:       ActionBlock7,
 	}
 
 	DeleteBlock = Block{ // Hacky delete icon in menu
@@ -210,10 +352,20 @@ var (
 		Title:        "Spooky Delete",
 		IconEnabled:  "icons_04",
 		IconDisabled: "icons_desaturated_04",
-		Key:          "d",
+		Action:       ActionDeleteMode,
 	}
 )
 
+// ByAttraction sorts placements by how attractive their blocks are, most
+// attractive first.
+type ByAttraction []BlockPlacement
+
+func (a ByAttraction) Len() int      { return len(a) }
+func (a ByAttraction) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a ByAttraction) Less(i, j int) bool {
+	return a[i].Block.Attraction > a[j].Block.Attraction
+}
+
 var (
-	HudBlocks = []*Block{&DeleteBlock, &SkellyBlock, &SpikesBlock, &CornerBlock, &ScaryBox}
+	HudBlocks = []*Block{&DeleteBlock, &SkellyBlock, &SpikesBlock, &CornerBlock, &ScaryBox, &DoorBlock, &LureBlock, &ShadowBlock}
 )
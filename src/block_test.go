@@ -0,0 +1,92 @@
+// Copyright 2015 Pikkpoiss
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"testing"
+)
+
+func loadFrameNames(t *testing.T) map[string]bool {
+	var sheet struct {
+		Frames []struct {
+			Filename string
+		}
+	}
+	data, err := ioutil.ReadFile("resources/spritesheet.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = json.Unmarshal(data, &sheet); err != nil {
+		t.Fatal(err)
+	}
+	names := map[string]bool{}
+	for _, frame := range sheet.Frames {
+		names[frame.Filename] = true
+	}
+	return names
+}
+
+func TestBlocksHaveTheirOwnSprites(t *testing.T) {
+	var (
+		frames = loadFrameNames(t)
+		owners = map[string]*Block{} // By layout of frames.
+	)
+	for _, block := range HudBlocks {
+		if block.IconEnabled == block.IconDisabled && !block.Door {
+			t.Errorf("Expected %v to have a disabled icon", block.Title)
+		}
+		for _, icon := range []string{block.IconEnabled, block.IconDisabled} {
+			if !frames[icon] {
+				t.Errorf("Expected icon %v for %v in the spritesheet", icon, block.Title)
+			}
+		}
+		// Blocks may share tiles, as long as they are laid out differently.
+		var look string
+		for _, variant := range block.Variants {
+			for _, row := range variant {
+				for _, template := range row {
+					if template != nil {
+						look += template.Frame
+					}
+					look += ","
+				}
+				look += ";"
+			}
+		}
+		if owner, ok := owners[look]; ok && look != "" {
+			t.Errorf("Expected %v not to look like %v", block.Title, owner.Title)
+		}
+		owners[look] = block
+		for _, variant := range block.Variants {
+			for _, row := range variant {
+				for _, template := range row {
+					if template == nil {
+						continue
+					}
+					for state, sequence := range template.Frames {
+						for _, i := range sequence {
+							if name := fmt.Sprintf(template.Frame, i); !frames[name] {
+								t.Errorf("Expected frame %v for %v in state %v", name, block.Title, state)
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+}
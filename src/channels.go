@@ -0,0 +1,67 @@
+// Copyright 2015 Pikkpoiss
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"time"
+)
+
+type channelInfo struct {
+	priority int
+	started  time.Time
+}
+
+// ChannelAllocator hands out mixer channels from a fixed range. Free
+// channels are preferred; when all are busy the oldest sound with the
+// lowest priority not above the request is cut off.
+type ChannelAllocator struct {
+	first     int
+	channels  []channelInfo
+	isPlaying func(channel int) bool
+}
+
+func NewChannelAllocator(first, count int, isPlaying func(channel int) bool) *ChannelAllocator {
+	return &ChannelAllocator{
+		first:     first,
+		channels:  make([]channelInfo, count),
+		isPlaying: isPlaying,
+	}
+}
+
+// Allocate returns a channel for a sound of the given priority, or false if
+// every channel is busy with something more important.
+func (a *ChannelAllocator) Allocate(priority int, now time.Time) (channel int, ok bool) {
+	var victim = -1
+	for i := range a.channels {
+		if !a.isPlaying(a.first + i) {
+			victim = i
+			break
+		}
+		info := a.channels[i]
+		if info.priority > priority {
+			continue
+		}
+		if victim == -1 ||
+			info.priority < a.channels[victim].priority ||
+			(info.priority == a.channels[victim].priority && info.started.Before(a.channels[victim].started)) {
+			victim = i
+		}
+	}
+	if victim == -1 {
+		return 0, false
+	}
+	a.channels[victim] = channelInfo{priority, now}
+	return a.first + victim, true
+}
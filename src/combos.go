@@ -0,0 +1,110 @@
+// Copyright 2015 Pikkpoiss
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+)
+
+const CombosFile = "resources/combos.json"
+
+// Combo is a bonus for placing two kinds of block close to each other.
+type Combo struct {
+	Name      string
+	Blocks    [2]string // Titles of the two blocks, in either order.
+	Distance  int32     // Most tiles apart the blocks may be.
+	FearBonus float64   // Added to the fear multiplier of both blocks.
+	Speed     float64   // Speed multiplier for mobs seen by either block, 0 for none.
+	InFront   bool      // The first block must stand in front of the second, in the same column.
+	blocks    [2]*Block
+}
+
+// ComboMatch is a combo formed by a placement with the placement at Partner.
+type ComboMatch struct {
+	Combo   *Combo
+	Partner Ivec2
+}
+
+type ComboCatalog struct {
+	Combos []*Combo
+}
+
+func LoadComboCatalog(path string) (catalog *ComboCatalog, err error) {
+	var data []byte
+	if data, err = ioutil.ReadFile(path); err != nil {
+		return
+	}
+	catalog = &ComboCatalog{}
+	if err = json.Unmarshal(data, catalog); err != nil {
+		return
+	}
+	for _, combo := range catalog.Combos {
+		for i, title := range combo.Blocks {
+			if combo.blocks[i] = blockByTitle(title); combo.blocks[i] == nil {
+				err = fmt.Errorf("Unknown block %v in combo %v in %v", title, combo.Name, path)
+				return
+			}
+		}
+	}
+	return
+}
+
+func blockByTitle(title string) *Block {
+	for _, block := range HudBlocks {
+		if block.Title == title {
+			return block
+		}
+	}
+	return nil
+}
+
+// Matches returns true if a and b are the two blocks of the combo and close
+// enough together.
+func (c *Combo) Matches(a, b BlockPlacement) bool {
+	var (
+		dx = absInt32(a.Pos.X() - b.Pos.X())
+		dy = absInt32(a.Pos.Y() - b.Pos.Y())
+	)
+	if dx > c.Distance || dy > c.Distance {
+		return false
+	}
+	if a.Block == c.blocks[1] && b.Block == c.blocks[0] {
+		a, b = b, a
+	} else if a.Block != c.blocks[0] || b.Block != c.blocks[1] {
+		return false
+	}
+	// Blocks lower down the screen are drawn in front.
+	return !c.InFront || (a.Pos.X() == b.Pos.X() && a.Pos.Y() < b.Pos.Y())
+}
+
+// Find returns the combos placement forms with the given blocks. Each combo
+// is only counted once per placement, and only with partners that work.
+func (c *ComboCatalog) Find(placement BlockPlacement, blocks map[Ivec2]BlockPlacement) (matches []ComboMatch) {
+	if c == nil {
+		return
+	}
+	for _, combo := range c.Combos {
+		for key, other := range blocks {
+			if other.Pos == placement.Pos || !other.Active() || !combo.Matches(placement, other) {
+				continue
+			}
+			matches = append(matches, ComboMatch{combo, key})
+			break
+		}
+	}
+	return
+}
@@ -0,0 +1,165 @@
+// Copyright 2015 Pikkpoiss
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func loadTestCombos(t *testing.T) map[string]*Combo {
+	catalog, err := LoadComboCatalog(CombosFile)
+	if err != nil {
+		t.Fatalf("Could not load combos: %v", err)
+	}
+	combos := map[string]*Combo{}
+	for _, combo := range catalog.Combos {
+		combos[combo.Name] = combo
+	}
+	return combos
+}
+
+func TestComboMatches(t *testing.T) {
+	var combos = loadTestCombos(t)
+	for _, ct := range []struct {
+		combo    string
+		a, b     BlockPlacement
+		expected bool
+	}{
+		{"Bed of Bones", BlockPlacement{Pos: Ivec2{4, 4}, Block: &SkellyBlock}, BlockPlacement{Pos: Ivec2{7, 1}, Block: &SpikesBlock}, true},
+		{"Bed of Bones", BlockPlacement{Pos: Ivec2{7, 1}, Block: &SpikesBlock}, BlockPlacement{Pos: Ivec2{4, 4}, Block: &SkellyBlock}, true},
+		{"Bed of Bones", BlockPlacement{Pos: Ivec2{4, 4}, Block: &SkellyBlock}, BlockPlacement{Pos: Ivec2{8, 4}, Block: &SpikesBlock}, false},
+		{"Bed of Bones", BlockPlacement{Pos: Ivec2{4, 4}, Block: &SkellyBlock}, BlockPlacement{Pos: Ivec2{5, 4}, Block: &SkellyBlock}, false},
+		{"Jump Scare", BlockPlacement{Pos: Ivec2{4, 3}, Block: &ScaryBox}, BlockPlacement{Pos: Ivec2{4, 4}, Block: &SkellyBlock}, true},
+		{"Jump Scare", BlockPlacement{Pos: Ivec2{4, 4}, Block: &SkellyBlock}, BlockPlacement{Pos: Ivec2{4, 3}, Block: &ScaryBox}, true},
+		{"Jump Scare", BlockPlacement{Pos: Ivec2{4, 5}, Block: &ScaryBox}, BlockPlacement{Pos: Ivec2{4, 4}, Block: &SkellyBlock}, false}, // Behind.
+		{"Jump Scare", BlockPlacement{Pos: Ivec2{3, 4}, Block: &ScaryBox}, BlockPlacement{Pos: Ivec2{4, 4}, Block: &SkellyBlock}, false}, // Beside.
+		{"Jump Scare", BlockPlacement{Pos: Ivec2{3, 3}, Block: &ScaryBox}, BlockPlacement{Pos: Ivec2{4, 4}, Block: &SkellyBlock}, false}, // Diagonal.
+		{"Jump Scare", BlockPlacement{Pos: Ivec2{4, 2}, Block: &ScaryBox}, BlockPlacement{Pos: Ivec2{4, 4}, Block: &SkellyBlock}, false}, // Too far.
+	} {
+		combo, ok := combos[ct.combo]
+		if !ok {
+			t.Fatalf("Expected a combo called %v in %v", ct.combo, CombosFile)
+		}
+		if matches := combo.Matches(ct.a, ct.b); matches != ct.expected {
+			t.Errorf("Expected %v at %v and %v at %v to match %v as %v", ct.a.Block.Title, ct.a.Pos, ct.b.Block.Title, ct.b.Pos, ct.expected, ct.combo)
+		}
+	}
+}
+
+func TestComboCatalogFind(t *testing.T) {
+	var (
+		combos  = loadTestCombos(t)
+		catalog = &ComboCatalog{[]*Combo{combos["Bed of Bones"], combos["Jump Scare"]}}
+		bones   = BlockPlacement{Pos: Ivec2{4, 4}, Block: &SkellyBlock}
+		blocks  = map[Ivec2]BlockPlacement{
+			Ivec2{4, 4}: bones,
+			Ivec2{6, 4}: BlockPlacement{Pos: Ivec2{6, 4}, Block: &SpikesBlock, Health: SpikesBlock.Health},
+			Ivec2{2, 4}: BlockPlacement{Pos: Ivec2{2, 4}, Block: &SpikesBlock, Health: SpikesBlock.Health},
+			Ivec2{4, 3}: BlockPlacement{Pos: Ivec2{4, 3}, Block: &ScaryBox, Health: ScaryBox.Health},
+		}
+		found = map[string]int{}
+	)
+	for _, match := range catalog.Find(bones, blocks) {
+		found[match.Combo.Name]++
+		if partner := blocks[match.Partner]; partner.Pos == bones.Pos {
+			t.Errorf("Expected %v not to pair the block with itself", match.Combo.Name)
+		}
+	}
+	if found["Bed of Bones"] != 1 || found["Jump Scare"] != 1 {
+		t.Errorf("Expected each combo to be found once, got %v", found)
+	}
+	var empty *ComboCatalog
+	if matches := empty.Find(bones, blocks); len(matches) != 0 {
+		t.Errorf("Expected no combos without a catalog, got %v", matches)
+	}
+}
+
+func TestComboCatalogFindSkipsBrokenPartners(t *testing.T) {
+	var (
+		combos  = loadTestCombos(t)
+		catalog = &ComboCatalog{[]*Combo{combos["Bed of Bones"]}}
+		bones   = BlockPlacement{Pos: Ivec2{4, 4}, Block: &SkellyBlock, Health: SkellyBlock.Health}
+		broken  = BlockPlacement{Pos: Ivec2{6, 4}, Block: &SpikesBlock}
+		working = BlockPlacement{Pos: Ivec2{2, 4}, Block: &SpikesBlock, Health: SpikesBlock.Health}
+		blocks  = map[Ivec2]BlockPlacement{bones.Pos: bones, broken.Pos: broken, working.Pos: working}
+	)
+	// Map order is random, so try enough times to hit the broken partner first.
+	for i := 0; i < 20; i++ {
+		matches := catalog.Find(bones, blocks)
+		if len(matches) != 1 || matches[0].Partner != working.Pos {
+			t.Fatalf("Expected the working spikes as the partner, got %v", matches)
+		}
+	}
+	blocks[working.Pos] = BlockPlacement{Pos: working.Pos, Block: &SpikesBlock, Health: SpikesBlock.Health, DisabledFor: time.Second}
+	if matches := catalog.Find(bones, blocks); len(matches) != 0 {
+		t.Errorf("Expected no combo with only inactive partners, got %v", matches)
+	}
+}
+
+func TestLoadComboCatalog(t *testing.T) {
+	for _, lt := range []struct {
+		contents string
+		ok       bool
+	}{
+		{`{"Combos": [{"Name": "Pair", "Blocks": ["Mr. Bones", "Unscary Box"], "Distance": 1}]}`, true},
+		{`{"Combos": [{"Name": "Pair", "Blocks": ["Mr. Bones", "Mr. Socks"], "Distance": 1}]}`, false},
+		{`{"Combos": [{"Name": "Pair", "Blocks": ["Mr. Bones"], "Distance": 1}]}`, false},
+		{`{"Combos": [`, false},
+	} {
+		file, err := ioutil.TempFile("", "combos")
+		if err != nil {
+			t.Fatal(err)
+		}
+		file.WriteString(lt.contents)
+		file.Close()
+		catalog, err := LoadComboCatalog(file.Name())
+		os.Remove(file.Name())
+		if ok := err == nil; ok != lt.ok {
+			t.Errorf("Expected loading %v to succeed %v, got %v", lt.contents, lt.ok, err)
+			continue
+		}
+		if lt.ok && catalog.Combos[0].blocks[1] != &ScaryBox {
+			t.Errorf("Expected combo blocks to be looked up by title")
+		}
+	}
+	if _, err := LoadComboCatalog("resources/no_such_combos.json"); err == nil {
+		t.Errorf("Expected an error for a missing file")
+	}
+}
+
+func TestComboSpeedNeedsActivePartner(t *testing.T) {
+	var (
+		combos = loadTestCombos(t)
+		level  = newTestLevel(newTestGrid(12, 9))
+		pt     = Ivec2{3, 4} // Seen by the skeleton.
+	)
+	level.combos = &ComboCatalog{[]*Combo{combos["Rattling Cage"]}}
+	addTestBlock(t, level, &SkellyBlock, Ivec2{4, 4})
+	cage := addTestBlock(t, level, &CornerBlock, Ivec2{7, 4})
+	level.updateTerrain()
+	if speed := level.Grid.Speed(pt); speed != combos["Rattling Cage"].Speed {
+		t.Fatalf("Expected the combo to slow mobs to %v, got %v", combos["Rattling Cage"].Speed, speed)
+	}
+	placement := level.blocks[cage]
+	placement.DisabledFor = SkepticKind.DisableFor
+	level.blocks[cage] = placement
+	level.updateTerrain()
+	if speed := level.Grid.Speed(pt); speed <= combos["Rattling Cage"].Speed {
+		t.Errorf("Expected the combo to stop slowing mobs while the cage is disabled, got %v", speed)
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright 2015 Pikkpoiss
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+const ConfigDirName = "screamporium"
+
+// ConfigPath returns where the named per-user file should be kept, creating
+// the containing directory if needed. Falls back to the working directory.
+func ConfigPath(name string) string {
+	var dir string
+	switch {
+	case os.Getenv("APPDATA") != "":
+		dir = filepath.Join(os.Getenv("APPDATA"), ConfigDirName)
+	case os.Getenv("HOME") != "":
+		dir = filepath.Join(os.Getenv("HOME"), "."+ConfigDirName)
+	default:
+		return name
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return name
+	}
+	return filepath.Join(dir, name)
+}
+
+// LoadJSON reads path into v. A missing file is not an error and leaves v
+// untouched so callers can fill in defaults first.
+func LoadJSON(path string, v interface{}) (err error) {
+	var data []byte
+	if data, err = ioutil.ReadFile(path); err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		}
+		return
+	}
+	return json.Unmarshal(data, v)
+}
+
+func SaveJSON(path string, v interface{}) (err error) {
+	var data []byte
+	if data, err = json.MarshalIndent(v, "", "  "); err != nil {
+		return
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,126 @@
+// Copyright 2015 Pikkpoiss
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"container/heap"
+)
+
+// DistanceField holds the cheapest route from every tile of a grid to a
+// target. Slow tiles cost more to cross, and each tile stores the number of
+// steps along its route as well as its cost. The target itself has a cost of
+// 0 and -1 steps, so tiles next to it are 0 steps away.
+type DistanceField struct {
+	Target Ivec2
+	width  int32
+	height int32
+	costs  []int32
+	steps  []int32
+}
+
+func NewDistanceField(target Ivec2, width, height int32) *DistanceField {
+	return &DistanceField{
+		Target: target,
+		width:  width,
+		height: height,
+		costs:  make([]int32, width*height),
+		steps:  make([]int32, width*height),
+	}
+}
+
+func (f *DistanceField) index(pt Ivec2) (int32, bool) {
+	if pt.X() < 0 || pt.Y() < 0 || pt.X() >= f.width || pt.Y() >= f.height {
+		return 0, false
+	}
+	return pt.Y()*f.width + pt.X(), true
+}
+
+// Cost returns the cost of the route from pt to the target, or -1 if the
+// target can't be reached from pt.
+func (f *DistanceField) Cost(pt Ivec2) int32 {
+	if i, ok := f.index(pt); ok {
+		return f.costs[i]
+	}
+	return -1
+}
+
+// Steps returns the number of steps from pt to the tile next to the target,
+// or -1 for the target itself and tiles which can't reach it.
+func (f *DistanceField) Steps(pt Ivec2) int32 {
+	if i, ok := f.index(pt); ok {
+		return f.steps[i]
+	}
+	return -1
+}
+
+// Calculate fills the field using stepCost, which returns the cost of
+// stepping onto a tile or false if the tile can't be walked on.
+func (f *DistanceField) Calculate(adjacent func(Ivec2) []Ivec2, stepCost func(Ivec2) (int32, bool)) {
+	var (
+		queue = &pathQueue{pathNode{f.Target, 0}}
+		start int32
+		cost  int32
+	)
+	for i := range f.costs {
+		f.costs[i] = -1
+		f.steps[i] = -1
+	}
+	if i, ok := f.index(f.Target); ok {
+		f.costs[i] = 0
+	} else {
+		return
+	}
+	for queue.Len() > 0 {
+		node := heap.Pop(queue).(pathNode)
+		start, _ = f.index(node.pt)
+		if node.cost > f.costs[start] {
+			continue // Already reached through a cheaper route.
+		}
+		for _, adj := range adjacent(node.pt) {
+			step, ok := stepCost(adj)
+			if !ok {
+				continue
+			}
+			i, _ := f.index(adj)
+			cost = node.cost + step
+			if f.costs[i] == -1 || cost < f.costs[i] {
+				f.costs[i] = cost
+				f.steps[i] = f.steps[start] + 1
+				heap.Push(queue, pathNode{adj, cost})
+			}
+		}
+	}
+}
+
+type pathNode struct {
+	pt   Ivec2
+	cost int32
+}
+
+// pathQueue is a min-heap of path nodes ordered by cost.
+type pathQueue []pathNode
+
+func (q pathQueue) Len() int            { return len(q) }
+func (q pathQueue) Less(i, j int) bool  { return q[i].cost < q[j].cost }
+func (q pathQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *pathQueue) Push(x interface{}) { *q = append(*q, x.(pathNode)) }
+func (q *pathQueue) Pop() interface{} {
+	var (
+		old  = *q
+		node = old[len(old)-1]
+	)
+	*q = old[:len(old)-1]
+	return node
+}
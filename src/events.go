@@ -16,21 +16,261 @@ package main
 
 import (
 	"../lib/twodee"
+	"github.com/go-gl/mathgl/mgl32"
 )
 
 const (
 	PlayBackgroundMusic twodee.GameEventType = iota
 	PauseMusic
 	ResumeMusic
-	PlayPlaceBlockEffect
-	PlayMrBonesEffect
-	PlaySpikesEffect
-	PlayDeathEffect
+	MobSpawned
+	MobScared
+	MobDied
+	MobExited
+	MobScreamed
+	AttractionVisited
+	BlockPlaced
+	BlockRemoved
+	BlockSabotaged
+	DoorOpened
+	DoorClosed
+	GeldChanged
+	RatingChanged
+	AchievementUnlocked
+	ObjectiveCompleted
+	ObjectiveFailed
 	PlayerLost
 	PlayerWon
+	SaveFailed
 	SENTINEL
 )
 
 const (
 	NumGameEventTypes = int(SENTINEL)
 )
+
+// GameEventNames is used to refer to events from data files.
+var GameEventNames = map[string]twodee.GameEventType{
+	"PlayBackgroundMusic": PlayBackgroundMusic,
+	"PauseMusic":          PauseMusic,
+	"ResumeMusic":         ResumeMusic,
+	"MobSpawned":          MobSpawned,
+	"MobScared":           MobScared,
+	"MobDied":             MobDied,
+	"MobExited":           MobExited,
+	"MobScreamed":         MobScreamed,
+	"AttractionVisited":   AttractionVisited,
+	"BlockPlaced":         BlockPlaced,
+	"BlockRemoved":        BlockRemoved,
+	"BlockSabotaged":      BlockSabotaged,
+	"DoorOpened":          DoorOpened,
+	"DoorClosed":          DoorClosed,
+	"GeldChanged":         GeldChanged,
+	"RatingChanged":       RatingChanged,
+	"AchievementUnlocked": AchievementUnlocked,
+	"ObjectiveCompleted":  ObjectiveCompleted,
+	"ObjectiveFailed":     ObjectiveFailed,
+	"PlayerLost":          PlayerLost,
+	"PlayerWon":           PlayerWon,
+	"SaveFailed":          SaveFailed,
+}
+
+// Located is implemented by events which happen at a point in the world.
+type Located interface {
+	Location() mgl32.Vec2
+}
+
+// BlockSourced is implemented by events caused by a placed block.
+type BlockSourced interface {
+	SourceBlock() BlockPlacement
+}
+
+// MusicEvent is sent for PlayBackgroundMusic.
+type MusicEvent struct {
+	*twodee.BasicGameEvent
+	Level string
+}
+
+func NewMusicEvent(level string) *MusicEvent {
+	return &MusicEvent{
+		BasicGameEvent: twodee.NewBasicGameEvent(PlayBackgroundMusic),
+		Level:          level,
+	}
+}
+
+// MobEvent is sent for MobSpawned, MobDied, MobExited and MobScreamed.
+type MobEvent struct {
+	*twodee.BasicGameEvent
+	MobId  int
+	Pos    mgl32.Vec2
+	Fear   float64
+	Peak   float64 // Highest fear the mob reached during its visit.
+	Review float64 // Fear the mob remembers from its visit, for MobExited.
+}
+
+func NewMobEvent(t twodee.GameEventType, mob *Mob) *MobEvent {
+	return &MobEvent{
+		BasicGameEvent: twodee.NewBasicGameEvent(t),
+		MobId:          mob.Id,
+		Pos:            mob.Pos,
+		Fear:           mob.Fear,
+		Peak:           mob.Timeline.Peak,
+	}
+}
+
+func (e *MobEvent) Location() mgl32.Vec2 {
+	return e.Pos
+}
+
+// ScareEvent is sent as MobScared once per update for every block which
+// frightened at least one mob.
+type ScareEvent struct {
+	*twodee.BasicGameEvent
+	Placement BlockPlacement
+	Pos       mgl32.Vec2
+	MobIds    []int
+	Fear      float64 // Fear applied to each mob.
+}
+
+func NewScareEvent(placement BlockPlacement, pos mgl32.Vec2, mobIds []int, fear float64) *ScareEvent {
+	return &ScareEvent{
+		BasicGameEvent: twodee.NewBasicGameEvent(MobScared),
+		Placement:      placement,
+		Pos:            pos,
+		MobIds:         mobIds,
+		Fear:           fear,
+	}
+}
+
+func (e *ScareEvent) Location() mgl32.Vec2 {
+	return e.Pos
+}
+
+func (e *ScareEvent) SourceBlock() BlockPlacement {
+	return e.Placement
+}
+
+// BlockEvent is sent for BlockPlaced, BlockRemoved and BlockSabotaged.
+type BlockEvent struct {
+	*twodee.BasicGameEvent
+	Placement BlockPlacement
+	Pos       mgl32.Vec2
+}
+
+func NewBlockEvent(t twodee.GameEventType, placement BlockPlacement) *BlockEvent {
+	return &BlockEvent{
+		BasicGameEvent: twodee.NewBasicGameEvent(t),
+		Placement:      placement,
+		Pos:            placement.Center(),
+	}
+}
+
+func (e *BlockEvent) Location() mgl32.Vec2 {
+	return e.Pos
+}
+
+func (e *BlockEvent) SourceBlock() BlockPlacement {
+	return e.Placement
+}
+
+// AmountEvent is sent for GeldChanged and RatingChanged.
+type AmountEvent struct {
+	*twodee.BasicGameEvent
+	Value int
+	Delta int
+}
+
+func NewAmountEvent(t twodee.GameEventType, value, delta int) *AmountEvent {
+	return &AmountEvent{
+		BasicGameEvent: twodee.NewBasicGameEvent(t),
+		Value:          value,
+		Delta:          delta,
+	}
+}
+
+type AchievementEvent struct {
+	*twodee.BasicGameEvent
+	Achievement *Achievement
+}
+
+func NewAchievementEvent(achievement *Achievement) *AchievementEvent {
+	return &AchievementEvent{
+		BasicGameEvent: twodee.NewBasicGameEvent(AchievementUnlocked),
+		Achievement:    achievement,
+	}
+}
+
+// ObjectiveEvent is sent for ObjectiveCompleted and ObjectiveFailed.
+type ObjectiveEvent struct {
+	*twodee.BasicGameEvent
+	Objective *Objective
+}
+
+func NewObjectiveEvent(t twodee.GameEventType, objective *Objective) *ObjectiveEvent {
+	return &ObjectiveEvent{
+		BasicGameEvent: twodee.NewBasicGameEvent(t),
+		Objective:      objective,
+	}
+}
+
+// GameOverEvent is sent for PlayerWon and PlayerLost.
+type GameOverEvent struct {
+	*twodee.BasicGameEvent
+	Geld   int
+	Rating int
+	Stats  Stats
+}
+
+func NewGameOverEvent(t twodee.GameEventType, state *State, stats Stats) *GameOverEvent {
+	return &GameOverEvent{
+		BasicGameEvent: twodee.NewBasicGameEvent(t),
+		Geld:           state.Geld,
+		Rating:         state.Rating,
+		Stats:          stats,
+	}
+}
+
+// SaveFailedEvent is sent as SaveFailed when progress couldn't be written to
+// disk.
+type SaveFailedEvent struct {
+	*twodee.BasicGameEvent
+	What string
+	Err  error
+}
+
+func NewSaveFailedEvent(what string, err error) *SaveFailedEvent {
+	return &SaveFailedEvent{
+		BasicGameEvent: twodee.NewBasicGameEvent(SaveFailed),
+		What:           what,
+		Err:            err,
+	}
+}
+
+type subscription struct {
+	eventType  twodee.GameEventType
+	observerId int
+}
+
+// Subscriptions tracks observers added to a GameEventHandler so that they
+// can all be removed at once.
+type Subscriptions struct {
+	handler *twodee.GameEventHandler
+	subs    []subscription
+}
+
+func NewSubscriptions(handler *twodee.GameEventHandler) *Subscriptions {
+	return &Subscriptions{
+		handler: handler,
+	}
+}
+
+func (s *Subscriptions) Add(t twodee.GameEventType, callback func(twodee.GETyper)) {
+	s.subs = append(s.subs, subscription{t, s.handler.AddObserver(t, callback)})
+}
+
+func (s *Subscriptions) Delete() {
+	for _, sub := range s.subs {
+		s.handler.RemoveObserver(sub.eventType, sub.observerId)
+	}
+	s.subs = s.subs[0:0]
+}
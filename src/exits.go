@@ -0,0 +1,98 @@
+// Copyright 2015 Pikkpoiss
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+)
+
+const ExitsFile = "resources/exits.json"
+
+// Extra route cost a visitor is willing to pay to leave by an exit they
+// prefer.
+const ExitDetourCost = 10 * BaseStepCost
+
+// ExitKind determines which visitors head for an exit and what happens when
+// they leave through it.
+type ExitKind struct {
+	Name        string
+	Marker      string  // Object type used for this kind of exit in maps.
+	MinFear     float64 // Visitors with fear in this range prefer this exit.
+	MaxFear     float64
+	GeldFactor  float64 // Multiplies the Geld paid by visitors leaving here.
+	RatingBonus float64 // Added to the fear of visitors leaving here.
+}
+
+// ExitCatalog lists the kinds of exit maps may use. There must be one for
+// MarkerSink, which is also used for maps without any exits.
+type ExitCatalog struct {
+	Kinds []*ExitKind
+}
+
+func LoadExitCatalog(path string) (catalog *ExitCatalog, err error) {
+	var data []byte
+	if data, err = ioutil.ReadFile(path); err != nil {
+		return
+	}
+	catalog = &ExitCatalog{}
+	if err = json.Unmarshal(data, catalog); err != nil {
+		return
+	}
+	for _, kind := range catalog.Kinds {
+		switch {
+		case kind.Marker == "":
+			err = fmt.Errorf("No marker for exit %v in %v", kind.Name, path)
+		case kind.MinFear > kind.MaxFear:
+			err = fmt.Errorf("Empty fear range for exit %v in %v", kind.Name, path)
+		case kind.GeldFactor < 0:
+			err = fmt.Errorf("Negative GeldFactor for exit %v in %v", kind.Name, path)
+		}
+		if err != nil {
+			return
+		}
+	}
+	if catalog.Find(MarkerSink) == nil {
+		err = fmt.Errorf("No %v exit in %v", MarkerSink, path)
+	}
+	return
+}
+
+// Find returns the kind of exit used for marker, or nil if there is none.
+func (c *ExitCatalog) Find(marker string) *ExitKind {
+	for _, kind := range c.Kinds {
+		if kind.Marker == marker {
+			return kind
+		}
+	}
+	return nil
+}
+
+type Exit struct {
+	Pos  Ivec2
+	Kind *ExitKind
+}
+
+// Prefers returns true if a visitor with the given fear prefers this exit.
+func (e Exit) Prefers(fear float64) bool {
+	return fear >= e.Kind.MinFear && fear <= e.Kind.MaxFear
+}
+
+// Geld returns how much a visitor with the given fear pays when leaving.
+func (e Exit) Geld(fear float64) int {
+	return int(math.Floor(fear*e.Kind.GeldFactor + 0.5))
+}
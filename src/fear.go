@@ -0,0 +1,112 @@
+// Copyright 2015 Pikkpoiss
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"math"
+	"time"
+)
+
+// CalmFear is the fear of a mob which has not been scared at all.
+const CalmFear = 1.0
+
+const (
+	ScreamThreshold = 8.0 // Mobs scream once their fear passes this.
+	ScreamRearm     = 6.0 // Mobs may scream again once calmed below this.
+	ScreamRange     = 3.0
+	ScreamFear      = 1.0 // Fear added to every mob in range of a scream.
+	ScreamMaxFear   = 9.5 // Screams alone never scare anyone to death.
+	MaxScreamChain  = 3   // Screams caused by screams caused by ... a block.
+)
+
+// Temperament describes how a mob's fear changes over time.
+type Temperament struct {
+	DecayPerSec   float64       // Fear lost per second while nothing scares the mob.
+	DecayDelay    time.Duration // How long after a scare before fear starts to decay.
+	ToleranceGain float64       // Tolerance gained per point of fear taken.
+	MaxTolerance  float64       // Fraction of incoming fear tolerance can ignore.
+	Habituation   float64       // How quickly a kind of scare stops working.
+	PeakWeight    float64       // How much the peak counts in a review over the end.
+	SampleEvery   time.Duration // How often fear is recorded in the timeline.
+}
+
+var DefaultTemperament = Temperament{
+	DecayPerSec:   0.25,
+	DecayDelay:    2 * time.Second,
+	ToleranceGain: 0.05,
+	MaxTolerance:  0.6,
+	Habituation:   0.3,
+	PeakWeight:    0.5,
+	SampleEvery:   time.Second,
+}
+
+// FearTimeline records a mob's fear over its visit.
+type FearTimeline struct {
+	Samples []float64
+	Peak    float64
+	elapsed time.Duration
+}
+
+// Record adds the current fear to the timeline if enough time has passed
+// since the last sample.
+func (t *FearTimeline) Record(elapsed time.Duration, every time.Duration, fear float64) {
+	t.Peak = math.Max(t.Peak, fear)
+	t.elapsed += elapsed
+	if len(t.Samples) > 0 && t.elapsed < every {
+		return
+	}
+	t.elapsed = 0
+	t.Samples = append(t.Samples, fear)
+}
+
+// Reset empties the timeline so it can be reused for another visit.
+func (t *FearTimeline) Reset() {
+	t.Samples = t.Samples[0:0]
+	t.Peak = 0
+	t.elapsed = 0
+}
+
+// Review returns the fear a visitor remembers once they leave, which weighs
+// the scariest moment against how scared they were at the end.
+func (t *Temperament) Review(timeline *FearTimeline, end float64) float64 {
+	peak := math.Max(timeline.Peak, end)
+	return t.PeakWeight*peak + (1-t.PeakWeight)*end
+}
+
+// Absorb returns how much of fear a mob with the given tolerance takes, and
+// the mob's tolerance afterwards. Calming effects are never reduced.
+func (t *Temperament) Absorb(fear, tolerance float64) (float64, float64) {
+	if fear <= 0 {
+		return fear, tolerance
+	}
+	fear *= 1 - tolerance
+	return fear, math.Min(t.MaxTolerance, tolerance+fear*t.ToleranceGain)
+}
+
+// Habituate returns how much of fear a scare has left after the mob has
+// already taken exposure fear from the same kind of block.
+func (t *Temperament) Habituate(fear, exposure float64) float64 {
+	return fear / (1 + exposure*t.Habituation)
+}
+
+// Decay returns fear after elapsed, given how long it has been since the mob
+// was last scared and how much the ground under it helps recovery.
+func (t *Temperament) Decay(fear float64, elapsed, sinceScare time.Duration, recovery float64) float64 {
+	var loss = recovery * elapsed.Seconds()
+	if sinceScare >= t.DecayDelay {
+		loss += t.DecayPerSec * elapsed.Seconds()
+	}
+	return math.Max(math.Min(CalmFear, fear), fear-loss)
+}
@@ -0,0 +1,115 @@
+// Copyright 2015 Pikkpoiss
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestMob() *Mob {
+	return &Mob{
+		Fear:        CalmFear,
+		Temperament: &DefaultTemperament,
+		Exposure:    map[*Block]float64{},
+	}
+}
+
+func TestVarietyBeatsRepetition(t *testing.T) {
+	var (
+		repeated = newTestMob()
+		varied   = newTestMob()
+	)
+	for i := 0; i < 4; i++ {
+		repeated.Scare(&SkellyBlock, 1.0)
+	}
+	for _, block := range []*Block{&SkellyBlock, &SpikesBlock, &CornerBlock, &DoorBlock} {
+		varied.Scare(block, 1.0)
+	}
+	if repeated.Fear >= varied.Fear {
+		t.Errorf("Expected varied scares to beat repeated ones, got %v and %v", varied.Fear, repeated.Fear)
+	}
+}
+
+func TestReviewRemembersPeak(t *testing.T) {
+	var mob = newTestMob()
+	mob.Scare(&SkellyBlock, 6.0)
+	peak := mob.Fear
+	mob.Fear = CalmFear
+	if review := mob.Review(); review <= CalmFear || review >= peak {
+		t.Errorf("Expected review between %v and %v, got %v", CalmFear, peak, review)
+	}
+}
+
+func TestDecay(t *testing.T) {
+	var temperament = DefaultTemperament
+	if fear := temperament.Decay(5, time.Second, time.Second, 0); fear != 5 {
+		t.Errorf("Expected no decay right after a scare, got %v", fear)
+	}
+	if fear := temperament.Decay(5, 2*time.Second, 3*time.Second, 0); fear != 4.5 {
+		t.Errorf("Expected 0.5 fear lost over 2s, got %v", fear)
+	}
+	if fear := temperament.Decay(5, 2*time.Second, time.Second, 1); fear != 3 {
+		t.Errorf("Expected recovery to work straight away, got %v", fear)
+	}
+	if fear := temperament.Decay(1.2, 10*time.Second, time.Minute, 1); fear != CalmFear {
+		t.Errorf("Expected fear to stop at calm, got %v", fear)
+	}
+	if fear := temperament.Decay(0.5, time.Second, time.Minute, 0); fear != 0.5 {
+		t.Errorf("Expected calmed down mobs to stay calm, got %v", fear)
+	}
+}
+
+func TestAbsorbBuildsTolerance(t *testing.T) {
+	var (
+		temperament = DefaultTemperament
+		fear        float64
+		tolerance   float64
+	)
+	fear, tolerance = temperament.Absorb(4, 0)
+	if fear != 4 || tolerance != 0.2 {
+		t.Errorf("Expected full fear and 0.2 tolerance, got %v and %v", fear, tolerance)
+	}
+	fear, tolerance = temperament.Absorb(4, tolerance)
+	if fear != 3.2 || tolerance <= 0.2 {
+		t.Errorf("Expected tolerance to ignore a fifth of the fear, got %v and %v", fear, tolerance)
+	}
+	if _, tolerance = temperament.Absorb(100, tolerance); tolerance != temperament.MaxTolerance {
+		t.Errorf("Expected tolerance to be capped, got %v", tolerance)
+	}
+	if fear, _ = temperament.Absorb(-2, tolerance); fear != -2 {
+		t.Errorf("Expected calming not to be absorbed, got %v", fear)
+	}
+}
+
+func TestTimelineSampling(t *testing.T) {
+	var timeline FearTimeline
+	for i, fear := range []float64{2, 3, 9, 4, 5} {
+		timeline.Record(400*time.Millisecond, time.Second, fear)
+		if i == 0 && len(timeline.Samples) != 1 {
+			t.Fatalf("Expected the first fear to be recorded straight away")
+		}
+	}
+	if len(timeline.Samples) != 2 || timeline.Samples[1] != 4 {
+		t.Errorf("Expected a sample each second, got %v", timeline.Samples)
+	}
+	if timeline.Peak != 9 {
+		t.Errorf("Expected peak between samples to be kept, got %v", timeline.Peak)
+	}
+	timeline.Reset()
+	if len(timeline.Samples) != 0 || timeline.Peak != 0 {
+		t.Errorf("Expected an empty timeline after reset")
+	}
+}
@@ -16,6 +16,7 @@ package main
 
 import (
 	"../lib/twodee"
+	"fmt"
 	"io/ioutil"
 	"time"
 )
@@ -26,6 +27,7 @@ type GameLayer struct {
 	spriteTexture        *twodee.Texture
 	app                  *Application
 	level                *Level
+	levels               *LevelCatalog
 	uiState              UiState
 	state                *State
 	playerLostObserverId int
@@ -54,6 +56,7 @@ func (l *GameLayer) Delete() {
 			PlayerLost, l.playerLostObserverId)
 		l.playerLostObserverId = 0
 	}
+	l.level.Delete()
 	l.gameRenderer.Delete()
 }
 
@@ -82,18 +85,10 @@ func (l *GameLayer) HandleEvent(evt twodee.Event) bool {
 		l.SetUiState(newState)
 	}
 
-	switch event := evt.(type) {
-	case *twodee.KeyEvent:
-		if event.Type == twodee.Release {
-			break
-		}
-		switch event.Code {
-		case twodee.KeyM:
-			if twodee.MusicIsPaused() {
-				l.app.GameEventHandler.Enqueue(twodee.NewBasicGameEvent(ResumeMusic))
-			} else {
-				l.app.GameEventHandler.Enqueue(twodee.NewBasicGameEvent(PauseMusic))
-			}
+	switch l.app.Input.Action(evt) {
+	case ActionToggleMusic:
+		if err := l.app.AudioSystem.ToggleMusic(); err != nil {
+			fmt.Printf("Could not save settings: %v\n", err)
 		}
 	}
 	return true
@@ -103,17 +98,21 @@ func (l *GameLayer) Reset() (err error) {
 	if err = l.loadSpritesheet(); err != nil {
 		return
 	}
-	if err = l.LoadLevel(); err != nil {
+	if l.levels, err = LoadLevelCatalog(LevelsFile); err != nil {
 		return
 	}
-	l.app.GameEventHandler.Enqueue(twodee.NewBasicGameEvent(PlayBackgroundMusic))
+	err = l.LoadLevel()
 	return
 }
 
 func (l *GameLayer) LoadLevel() (err error) {
-	if l.level, err = NewLevel(l.state, l.spriteSheet, l.app.GameEventHandler); err != nil {
+	if l.level != nil {
+		l.level.Delete()
+	}
+	if l.level, err = NewLevel(l.levels.Level(l.state.Level), l.state, l.spriteSheet, l.app.GameEventHandler); err != nil {
 		return
 	}
+	l.level.App = l.app
 	l.uiState = NewNormalUiState()
 	l.uiState.Register(l.level)
 	if l.gameRenderer != nil {
@@ -122,6 +121,7 @@ func (l *GameLayer) LoadLevel() (err error) {
 	if l.gameRenderer, err = NewGameRenderer(l.level, l.spriteSheet); err != nil {
 		return
 	}
+	l.app.GameEventHandler.Enqueue(NewMusicEvent(l.level.Def.Name))
 	return
 }
 
@@ -132,8 +132,17 @@ func (l *GameLayer) PlayerLost(e twodee.GETyper) {
 }
 
 func (l *GameLayer) PlayerWon(e twodee.GETyper) {
+	var result *LevelResult
+	if evt, ok := e.(*GameOverEvent); ok {
+		var err error
+		if result, err = l.app.Medals.Award(l.app.Settings.Profile, l.level.Def, NewScore(evt)); err != nil {
+			l.app.GameEventHandler.Enqueue(NewSaveFailedEvent("medals", err))
+		}
+	}
 	l.state.Reset()
+	l.state.Level++
 	l.state.SplashState = SplashWin
+	l.state.Result = result
 	l.LoadLevel()
 }
 
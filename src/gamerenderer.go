@@ -39,12 +39,13 @@ type GameRenderer struct {
 	spritesStatic    []twodee.SpriteConfig
 	spritesHighlight []twodee.SpriteConfig
 	spritesDecals    []twodee.SpriteConfig
+	spritesOverlay   []twodee.SpriteConfig
 }
 
 func NewGameRenderer(level *Level, sheet *twodee.Spritesheet) (renderer *GameRenderer, err error) {
 	var (
-		xsize = int(PxPerUnit) * int(level.Grid.Width())
-		ysize = int(PxPerUnit) * int(level.Grid.Height())
+		xsize = int(PxPerUnit) * int(level.Grid.ViewWidth())
+		ysize = int(PxPerUnit) * int(level.Grid.ViewHeight())
 	)
 	renderer = &GameRenderer{
 		sheet: sheet,
@@ -74,10 +75,13 @@ func (r *GameRenderer) Draw(level *Level) {
 	r.spritesHighlight = r.spritesHighlight[0:0]
 	r.spritesDynamic = r.spritesDynamic[0:0]
 	r.spritesDecals = r.spritesDecals[0:0]
+	r.spritesOverlay = r.spritesOverlay[0:0]
 	for x = 0; x < level.Grid.Width(); x++ {
 		for y = 0; y < level.Grid.Height(); y++ {
 			pt = Ivec2{x, y}
-			item = level.Grid.GetBg(pt)
+			if item = level.Grid.GetBg(pt); item == nil {
+				continue // Gap between floors.
+			}
 			r.spritesStatic = append(r.spritesStatic, r.gridSpriteConfig(
 				level,
 				r.sheet,
@@ -98,6 +102,26 @@ func (r *GameRenderer) Draw(level *Level) {
 			))
 		}
 	}
+	for _, layer := range level.Grid.Layers() {
+		for x = 0; x < level.Grid.Width(); x++ {
+			for y = 0; y < level.Grid.Height(); y++ {
+				pt = Ivec2{x, y}
+				frame := layer.Frame(pt)
+				if frame == "" {
+					continue
+				}
+				if layer.Above {
+					r.spritesOverlay = append(r.spritesOverlay, r.tileSpriteConfig(r.sheet, pt, frame))
+				} else {
+					r.spritesStatic = append(r.spritesStatic, r.tileSpriteConfig(r.sheet, pt, frame))
+				}
+			}
+		}
+	}
+	r.spritesDynamic = append(r.spritesDynamic, level.Avatar.SpriteConfig(r.sheet))
+	for _, minion := range level.Minions {
+		r.spritesDynamic = append(r.spritesDynamic, minion.SpriteConfig(r.sheet))
+	}
 	for _, mob := range level.Mobs {
 		if !mob.Enabled { // No enabled mobs after first disabled mob.
 			break
@@ -110,10 +134,21 @@ func (r *GameRenderer) Draw(level *Level) {
 		}
 		r.spritesDecals = append(r.spritesDecals, decal.SpriteConfig(r.sheet))
 	}
+	for _, ghost := range level.Ghosts {
+		if ghost.Enabled {
+			r.spritesDecals = append(r.spritesDecals, ghost.SpriteConfig(r.sheet))
+		}
+	}
+	for _, highlight := range level.DisabledHighlights() {
+		r.spritesHighlight = append(
+			r.spritesHighlight,
+			r.tileSpriteConfig(r.sheet, highlight.Pos, highlight.Frame),
+		)
+	}
 	for _, highlight := range level.Highlights {
 		r.spritesHighlight = append(
 			r.spritesHighlight,
-			r.highlightSpriteConfig(r.sheet, highlight.Pos, highlight.Frame),
+			r.tileSpriteConfig(r.sheet, highlight.Pos, highlight.Frame),
 		)
 	}
 	sort.Sort(ByY(r.spritesDynamic))
@@ -123,6 +158,9 @@ func (r *GameRenderer) Draw(level *Level) {
 	if len(r.spritesDecals) > 0 {
 		r.sprite.Draw(r.spritesDecals)
 	}
+	if len(r.spritesOverlay) > 0 {
+		r.sprite.Draw(r.spritesOverlay)
+	}
 	if len(r.spritesHighlight) > 0 {
 		r.sprite.Draw(r.spritesHighlight)
 	}
@@ -130,7 +168,7 @@ func (r *GameRenderer) Draw(level *Level) {
 	r.effects.Draw()
 }
 
-func (r *GameRenderer) highlightSpriteConfig(sheet *twodee.Spritesheet, pt Ivec2, name string) twodee.SpriteConfig {
+func (r *GameRenderer) tileSpriteConfig(sheet *twodee.Spritesheet, pt Ivec2, name string) twodee.SpriteConfig {
 	frame := sheet.GetFrame(name)
 	return twodee.SpriteConfig{
 		View: twodee.ModelViewConfig{
@@ -143,9 +181,12 @@ func (r *GameRenderer) highlightSpriteConfig(sheet *twodee.Spritesheet, pt Ivec2
 }
 
 func (r *GameRenderer) gridSpriteConfig(level *Level, sheet *twodee.Spritesheet, x, y float32, item *GridItem) twodee.SpriteConfig {
-	var frame *twodee.SpritesheetFrame
-	if level.State.Debug && item.Distance() >= 0 && item.Distance() < 16 {
-		frame = sheet.GetFrame(fmt.Sprintf("numbered_squares_%02v", item.Distance()))
+	var (
+		frame *twodee.SpritesheetFrame
+		dist  = level.Grid.Distance(Ivec2{int32(x), int32(y)})
+	)
+	if level.State.Debug && dist >= 0 && dist < 16 {
+		frame = sheet.GetFrame(fmt.Sprintf("numbered_squares_%02v", dist))
 	} else {
 		frame = sheet.GetFrame(item.Frame())
 	}
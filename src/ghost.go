@@ -0,0 +1,128 @@
+// Copyright 2015 Pikkpoiss
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"../lib/twodee"
+	"github.com/go-gl/mathgl/mgl32"
+	"math"
+	"math/rand"
+	"time"
+)
+
+const (
+	MaxGhosts         = 8
+	GhostLifetime     = 30 * time.Second
+	GhostGrace        = 15 * time.Second // Ghosts around longer than this cost rating.
+	GhostPenaltyEvery = 5 * time.Second
+	GhostPenalty      = 0.25 // A fraction of DeathPenalty, taken every GhostPenaltyEvery.
+	GhostSpeed        = 1.0
+	GhostRange        = 2.5
+	GhostFearPerSec   = 1.5
+	GhostMaxFear      = 9.5 // Ghosts never scare anyone to death themselves.
+)
+
+// Ghost is what is left of a visitor who was scared to death. Ghosts drift
+// through walls around the floor they died on, frightening the living, until
+// they fade away.
+type Ghost struct {
+	Pos       mgl32.Vec2
+	Dest      mgl32.Vec2
+	Age       time.Duration
+	Enabled   bool
+	bounds    twodee.Rectangle
+	penalties int
+	left      bool
+}
+
+func NewGhost() *Ghost {
+	return &Ghost{}
+}
+
+// Activate raises a ghost at pos, confined to bounds.
+func (g *Ghost) Activate(pos mgl32.Vec2, bounds twodee.Rectangle) {
+	g.Pos = pos
+	g.Dest = pos
+	g.Age = 0
+	g.Enabled = true
+	g.bounds = bounds
+	g.penalties = 0
+}
+
+func (g *Ghost) Disable() {
+	g.Enabled = false
+}
+
+// Expired returns true once the ghost has faded away.
+func (g *Ghost) Expired() bool {
+	return g.Age >= GhostLifetime
+}
+
+// Update moves the ghost and returns true if it has lingered long enough to
+// cost another rating penalty.
+func (g *Ghost) Update(elapsed time.Duration) bool {
+	var (
+		step   = float32(GhostSpeed * elapsed.Seconds())
+		toDest = g.Dest.Sub(g.Pos)
+	)
+	g.Age += elapsed
+	if toDest.Len() <= step {
+		g.Pos = g.Dest
+		g.Dest = mgl32.Vec2{
+			g.bounds.Min.X() + rand.Float32()*(g.bounds.Max.X()-g.bounds.Min.X()),
+			g.bounds.Min.Y() + rand.Float32()*(g.bounds.Max.Y()-g.bounds.Min.Y()),
+		}
+	} else {
+		g.Pos = g.Pos.Add(toDest.Normalize().Mul(step))
+		g.left = toDest.X() < 0
+	}
+	if g.Age < GhostGrace || g.Expired() {
+		return false
+	}
+	if due := int((g.Age-GhostGrace)/GhostPenaltyEvery) + 1; due > g.penalties {
+		g.penalties = due
+		return true
+	}
+	return false
+}
+
+// Haunt frightens mob if it is close enough.
+func (g *Ghost) Haunt(elapsed time.Duration, mob *Mob) {
+	if mob.Pos.Sub(g.Pos).Len() > GhostRange {
+		return
+	}
+	if fear := math.Min(GhostFearPerSec*elapsed.Seconds(), GhostMaxFear-mob.Fear); fear > 0 {
+		mob.IncreaseFear(fear)
+	}
+}
+
+func (g *Ghost) SpriteConfig(sheet *twodee.Spritesheet) twodee.SpriteConfig {
+	var (
+		frame          = sheet.GetFrame("ghost01_00")
+		bob            = float32(math.Sin(g.Age.Seconds()*3)) * 0.15
+		scaleX float32 = 1.0
+	)
+	if g.left {
+		scaleX = -1.0
+	}
+	return twodee.SpriteConfig{
+		View: twodee.ModelViewConfig{
+			g.Pos.X(), g.Pos.Y() + frame.Height/2.0 + bob, 0.0,
+			0, 0, 0,
+			scaleX, 1.0, 1.0,
+		},
+		Frame: frame.Frame,
+	}
+}
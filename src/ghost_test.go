@@ -0,0 +1,74 @@
+// Copyright 2015 Pikkpoiss
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"../lib/twodee"
+	"github.com/go-gl/mathgl/mgl32"
+	"testing"
+	"time"
+)
+
+func TestGhostLifetime(t *testing.T) {
+	var (
+		ghost     = NewGhost()
+		penalties = 0
+		elapsed   time.Duration
+	)
+	ghost.Activate(mgl32.Vec2{2, 2}, twodee.Rect(0, 0, 4, 4))
+	for ; !ghost.Expired(); elapsed += time.Second {
+		if ghost.Update(time.Second) {
+			penalties++
+			if elapsed < GhostGrace-time.Second {
+				t.Errorf("Expected no penalty during the grace period, got one after %v", elapsed)
+			}
+		}
+		if pos := ghost.Pos; pos.X() < 0 || pos.Y() < 0 || pos.X() > 4 || pos.Y() > 4 {
+			t.Fatalf("Expected ghost to stay within its floor, got %v", ghost.Pos)
+		}
+	}
+	if elapsed != GhostLifetime {
+		t.Errorf("Expected ghost to fade after %v, got %v", GhostLifetime, elapsed)
+	}
+	if expected := int((GhostLifetime - GhostGrace) / GhostPenaltyEvery); penalties != expected {
+		t.Errorf("Expected %v penalties, got %v", expected, penalties)
+	}
+	if float64(penalties)*GhostPenalty >= DeathPenalty {
+		t.Errorf("Expected a lingering ghost to cost less than a death")
+	}
+}
+
+func TestAddGhostFallsBackToDecal(t *testing.T) {
+	g, err := newGrid(&TiledMap{Floors: []*TiledFloor{newTestFloor("Ground", 6, 6, Ivec2{0, 0})}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	level := newTestLevel(g)
+	for i := 0; i < MaxGhosts; i++ {
+		level.AddGhost(mgl32.Vec2{3, 3})
+	}
+	for i, ghost := range level.Ghosts {
+		if !ghost.Enabled {
+			t.Errorf("Expected ghost %v to be raised", i)
+		}
+	}
+	if level.ActiveDecalCount != 0 {
+		t.Errorf("Expected no decals while there are free ghosts")
+	}
+	level.AddGhost(mgl32.Vec2{3, 3})
+	if level.ActiveDecalCount != 1 {
+		t.Errorf("Expected a floating decal once every ghost is in use")
+	}
+}
@@ -16,7 +16,9 @@ package main
 
 import (
 	"../lib/twodee"
+	"fmt"
 	"github.com/go-gl/mathgl/mgl32"
+	"math"
 	"time"
 )
 
@@ -34,37 +36,296 @@ func (i Ivec2) Plus(a Ivec2) Ivec2 {
 	return Ivec2{i[0] + a[0], i[1] + a[1]}
 }
 
+const (
+	BaseStepCost    = 10  // Path cost of crossing a tile at normal speed.
+	MinTerrainSpeed = 0.1 // Mobs never move slower than this multiplier.
+)
+
+// Number of empty tiles between floors.
+const FloorGap = 1
+
+// Floor is one storey of the house. All floors share one grid, where they are
+// laid out side by side, and are only connected to each other by stairs.
+type Floor struct {
+	Name   string
+	Offset Ivec2
+	Width  int32
+	Height int32
+}
+
+func (f Floor) Contains(pt Ivec2) bool {
+	return pt.X() >= f.Offset.X() && pt.X() < f.Offset.X()+f.Width &&
+		pt.Y() >= f.Offset.Y() && pt.Y() < f.Offset.Y()+f.Height
+}
+
+// Bounds returns the area covered by the floor in world coordinates.
+func (f Floor) Bounds() twodee.Rectangle {
+	return twodee.Rect(
+		float32(f.Offset.X()),
+		float32(f.Offset.Y()),
+		float32(f.Offset.X()+f.Width),
+		float32(f.Offset.Y()+f.Height),
+	)
+}
+
 type Grid struct {
 	background *twodee.Grid
 	grid       *twodee.Grid
+	floors     []Floor
+	viewWidth  int32
+	viewHeight int32
+	stairs     map[Ivec2][]Ivec2
+	layers     []*MapLayer
+	markers    []MapMarker
+	modifiers  map[Ivec2]float64
+	pathCosts  map[Ivec2]float64
 	sources    []Ivec2
-	sink       Ivec2
+	sinks      []*DistanceField
+	targets    map[Ivec2]*DistanceField
 }
 
-func NewGrid() (g *Grid, err error) {
-	var (
-		background *twodee.Grid
-		grid       *twodee.Grid
-	)
-	if background, err = LoadTiledMap("resources/maps/map01.tmx"); err != nil {
+func NewGrid(path string) (g *Grid, err error) {
+	var tiled *TiledMap
+	if tiled, err = LoadTiledMap(path); err != nil {
 		return
 	}
-	grid = twodee.NewGrid(background.Width, background.Height, 1.0)
+	return newGrid(tiled)
+}
+
+// newGrid lays the floors of the map out side by side and links up their
+// stairs.
+func newGrid(tiled *TiledMap) (g *Grid, err error) {
+	var (
+		width  int32
+		height int32
+		layers = map[string]*MapLayer{}
+	)
 	g = &Grid{
-		background: background,
-		grid:       grid,
+		stairs:    map[Ivec2][]Ivec2{},
+		modifiers: map[Ivec2]float64{},
+		pathCosts: map[Ivec2]float64{},
+	}
+	for i, floor := range tiled.Floors {
+		if i > 0 {
+			width += FloorGap
+		}
+		g.floors = append(g.floors, Floor{
+			Name:   floor.Name,
+			Offset: Ivec2{width, 0},
+			Width:  floor.Background.Width,
+			Height: floor.Background.Height,
+		})
+		width += floor.Background.Width
+		if floor.Background.Height > height {
+			height = floor.Background.Height
+		}
+		if floor.Background.Width > g.viewWidth {
+			g.viewWidth = floor.Background.Width
+		}
+	}
+	g.viewHeight = height
+	g.background = twodee.NewGrid(width, height, 1.0)
+	g.grid = twodee.NewGrid(width, height, 1.0)
+	for _, name := range MapLayerNames {
+		layers[name] = &MapLayer{
+			Name:   name,
+			Above:  name == "overlay",
+			width:  width,
+			frames: make([]string, width*height),
+		}
+	}
+	for i, floor := range tiled.Floors {
+		var offset = g.floors[i].Offset
+		for x := int32(0); x < floor.Background.Width; x++ {
+			for y := int32(0); y < floor.Background.Height; y++ {
+				if item, ok := floor.Background.Get(x, y).(*GridItem); ok {
+					g.background.Set(offset.X()+x, offset.Y()+y, item)
+				}
+			}
+		}
+		for _, layer := range floor.Layers {
+			for x := int32(0); x < floor.Background.Width; x++ {
+				for y := int32(0); y < floor.Background.Height; y++ {
+					pt := offset.Plus(Ivec2{x, y})
+					layers[layer.Name].frames[pt.Y()*width+pt.X()] = layer.Frame(Ivec2{x, y})
+				}
+			}
+		}
+		for _, marker := range floor.Markers {
+			marker.Pos = marker.Pos.Plus(offset)
+			marker.Floor = i
+			g.markers = append(g.markers, marker)
+		}
+	}
+	for _, name := range MapLayerNames {
+		for _, frame := range layers[name].frames {
+			if frame != "" {
+				g.layers = append(g.layers, layers[name])
+				break
+			}
+		}
+	}
+	err = g.linkStairs()
+	return
+}
+
+// linkStairs connects all stairs which share a name but are on different
+// floors.
+func (g *Grid) linkStairs() error {
+	for _, a := range g.markers {
+		if a.Kind != MarkerStairs {
+			continue
+		}
+		if !g.isWalkable(a.Pos) {
+			return fmt.Errorf("Stairs %v on floor %v can't be walked on", a.Name, a.Floor)
+		}
+		for _, b := range g.markers {
+			if b.Kind == MarkerStairs && b.Name == a.Name && b.Floor != a.Floor {
+				g.stairs[a.Pos] = append(g.stairs[a.Pos], b.Pos)
+			}
+		}
+	}
+	return nil
+}
+
+// Floors returns every floor, from the ground up.
+func (g *Grid) Floors() []Floor {
+	return g.floors
+}
+
+// FloorAt returns the index of the floor containing pt, or -1 for the gaps
+// between floors.
+func (g *Grid) FloorAt(pt Ivec2) int {
+	for i, floor := range g.floors {
+		if floor.Contains(pt) {
+			return i
+		}
+	}
+	return -1
+}
+
+// FloorView returns the world bounds of the view of the given floor. Every
+// floor's view is the same size.
+func (g *Grid) FloorView(floor int) twodee.Rectangle {
+	var offset = g.floors[floor].Offset
+	return twodee.Rect(
+		float32(offset.X()),
+		float32(offset.Y()),
+		float32(offset.X()+g.viewWidth),
+		float32(offset.Y()+g.viewHeight),
+	)
+}
+
+// ViewWidth returns the width of a single floor's view.
+func (g *Grid) ViewWidth() int32 {
+	return g.viewWidth
+}
+
+// ViewHeight returns the height of a single floor's view.
+func (g *Grid) ViewHeight() int32 {
+	return g.viewHeight
+}
+
+// IsStairs returns true if stairs lead from one point to the other.
+func (g *Grid) IsStairs(from, to Ivec2) bool {
+	for _, pt := range g.stairs[from] {
+		if pt == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Layers returns the map's decorative tile layers in drawing order.
+func (g *Grid) Layers() []*MapLayer {
+	return g.layers
+}
+
+// Markers returns the positions of all map markers of the given kind.
+func (g *Grid) Markers(kind string) (points []Ivec2) {
+	for _, marker := range g.markers {
+		if marker.Kind == kind {
+			points = append(points, marker.Pos)
+		}
 	}
 	return
 }
 
 func (g *Grid) AddSource(pt Ivec2) {
-	g.Set(pt, NewGridItem(false, "gate_01", nil))
+	g.Set(pt, NewGridItem(false, true, "gate_01", nil))
 	g.sources = append(g.sources, pt)
 }
 
-func (g *Grid) SetSink(pt Ivec2) {
-	g.Set(pt, NewGridItem(false, "gate_00", nil))
-	g.sink = pt
+// AddSink adds an exit at pt.
+func (g *Grid) AddSink(pt Ivec2) {
+	g.Set(pt, NewGridItem(false, true, "gate_00", nil))
+	g.sinks = append(g.sinks, NewDistanceField(pt, g.Width(), g.Height()))
+}
+
+// SinkField returns the distance field to the sink at pt, or nil if there is
+// no sink there.
+func (g *Grid) SinkField(pt Ivec2) *DistanceField {
+	for _, field := range g.sinks {
+		if field.Target == pt {
+			return field
+		}
+	}
+	return nil
+}
+
+// RouteCost returns the cost of the route from pt to the target of field, or
+// -1 if there is none.
+func (g *Grid) RouteCost(pt Ivec2, field *DistanceField) (cost int32) {
+	if g.isWalkable(pt) {
+		return field.Cost(pt)
+	}
+	cost = -1
+	for _, adj := range g.getAdjacent(pt) {
+		if c := field.Cost(adj); g.isWalkable(adj) && c >= 0 && (cost < 0 || c < cost) {
+			cost = c
+		}
+	}
+	return
+}
+
+// AddTarget keeps a distance field to pt up to date so mobs may find their
+// way there.
+func (g *Grid) AddTarget(pt Ivec2) {
+	if g.targets == nil {
+		g.targets = map[Ivec2]*DistanceField{}
+	}
+	g.targets[pt] = NewDistanceField(pt, g.Width(), g.Height())
+}
+
+func (g *Grid) RemoveTarget(pt Ivec2) {
+	delete(g.targets, pt)
+}
+
+// Target returns the distance field to pt, or nil if it isn't a target.
+func (g *Grid) Target(pt Ivec2) *DistanceField {
+	return g.targets[pt]
+}
+
+// Distance returns the number of steps from pt to the nearest sink, or -1 if
+// no sink can be reached.
+func (g *Grid) Distance(pt Ivec2) (dist int32) {
+	dist = -1
+	for _, field := range g.sinks {
+		if steps := field.Steps(pt); steps >= 0 && (dist < 0 || steps < dist) {
+			dist = steps
+		}
+	}
+	return
+}
+
+// canExit returns true if a mob at pt can reach any sink.
+func (g *Grid) canExit(pt Ivec2) bool {
+	for _, field := range g.sinks {
+		if field.Cost(pt) >= 0 {
+			return true
+		}
+	}
+	return false
 }
 
 func (g *Grid) Set(pt Ivec2, item *GridItem) {
@@ -79,8 +340,13 @@ func (g *Grid) IsBlockValid(placement BlockPlacement) (ok bool) {
 	ok = true
 	for y := 0; y < len(placement.Block.Variants[placement.Variant]); y++ {
 		for x := 0; x < len(placement.Block.Variants[placement.Variant][y]); x++ {
-			item = g.Get(pt.Plus(Ivec2{int32(x), int32(y)}))
-			if item != nil && !item.Passable() {
+			// Blocks may not overlap each other, even open doors, or walls.
+			if g.Get(pt.Plus(Ivec2{int32(x), int32(y)})) != nil {
+				ok = false
+				break
+			}
+			item = g.GetBg(pt.Plus(Ivec2{int32(x), int32(y)}))
+			if item == nil || !item.Passable() {
 				ok = false
 				break
 			}
@@ -105,7 +371,7 @@ func (g *Grid) SetBlock(placement BlockPlacement) (Ivec2, bool) {
 			if tmpl == nil {
 				continue
 			}
-			item := NewGridItem(tmpl.Passable, tmpl.Frame, tmpl.Frames)
+			item := NewGridItem(tmpl.Passable, tmpl.Opaque, tmpl.Frame, tmpl.Frames)
 			g.Set(
 				pt.Plus(Ivec2{int32(x), int32(y)}),
 				item,
@@ -149,6 +415,58 @@ func (g *Grid) UpdateBlockState(placement BlockPlacement, state BlockState) {
 	}
 }
 
+// SetDoorOpen opens or closes the door at the given placement. Open doors
+// can be walked and seen through.
+func (g *Grid) SetDoorOpen(placement BlockPlacement, open bool) {
+	var (
+		pt    = placement.Pos.Plus(placement.Block.Offset)
+		state = BlockNormal
+		item  *GridItem
+	)
+	if open {
+		state = BlockOpen
+	}
+	for y := 0; y < len(placement.Block.Variants[placement.Variant]); y++ {
+		for x := 0; x < len(placement.Block.Variants[placement.Variant][y]); x++ {
+			item = g.Get(pt.Plus(Ivec2{int32(x), int32(y)}))
+			if item != nil {
+				item.passable = open
+				item.opaque = !open
+				item.SetState(state)
+			}
+		}
+	}
+}
+
+// IsDoorOpen returns true if the door at the given placement is open.
+func (g *Grid) IsDoorOpen(placement BlockPlacement) bool {
+	item := g.Get(placement.Pos)
+	return item != nil && item.Passable()
+}
+
+// HasRoute returns true if every source, as well as every given point which
+// can be walked on, can reach a sink.
+func (g *Grid) HasRoute(points []Ivec2) bool {
+	for _, src := range g.sources {
+		reachable := false
+		for _, adj := range g.getAdjacent(src) {
+			if g.canExit(adj) {
+				reachable = true
+				break
+			}
+		}
+		if !reachable {
+			return false
+		}
+	}
+	for _, pt := range points {
+		if g.isWalkable(pt) && !g.canExit(pt) {
+			return false
+		}
+	}
+	return true
+}
+
 func (g *Grid) Width() int32 {
 	return g.grid.Width
 }
@@ -164,81 +482,132 @@ func (g *Grid) WorldToGrid(worldCoords mgl32.Vec2) Ivec2 {
 	}
 }
 
+// GetNextStepToSink returns the next step towards whichever sink is cheapest
+// to reach from pt.
 func (g *Grid) GetNextStepToSink(pt mgl32.Vec2) (out mgl32.Vec2, dist int32, valid bool) {
 	var (
-		gridPt = g.WorldToGrid(pt)
-		points []Ivec2
-		item   *GridItem
+		gridPt       = g.WorldToGrid(pt)
+		best   int32 = -1
 	)
-	dist = 9999999
-	points = g.getAdjacent(gridPt)
-	valid = false
-	for _, adj := range points {
-		item = g.Get(adj)
-		if item == nil {
-			item = g.GetBg(adj)
-		}
-		if item != nil && item.Passable() {
-			if item.Distance() < dist {
-				dist = item.Distance()
-				out = mgl32.Vec2{
-					g.grid.InversePosition(adj.X()),
-					g.grid.InversePosition(adj.Y()),
-				}
-				valid = true
-			}
+	for _, field := range g.sinks {
+		if cost := g.RouteCost(gridPt, field); cost >= 0 && (best < 0 || cost < best) {
+			best = cost
+			out, dist, valid = g.GetNextStep(pt, field)
 		}
 	}
 	return
 }
 
-func (g *Grid) resetDistances() {
+// GetNextStep returns the center of the adjacent tile along the cheapest
+// route to the target of field, along with its number of steps to the target.
+func (g *Grid) GetNextStep(pt mgl32.Vec2, field *DistanceField) (out mgl32.Vec2, dist int32, valid bool) {
 	var (
-		x    int32
-		y    int32
-		item *GridItem
-		pt   Ivec2
+		gridPt       = g.WorldToGrid(pt)
+		cost   int32 = math.MaxInt32
 	)
-	for x = 0; x < g.Width(); x++ {
-		for y = 0; y < g.Height(); y++ {
-			pt = Ivec2{x, y}
-			item = g.Get(pt)
-			if item == nil {
-				item = g.GetBg(pt)
-			}
-			if item != nil && item.Passable() {
-				item.SetDistance(-1)
+	for _, adj := range g.getLinked(gridPt) {
+		if !g.isWalkable(adj) || field.Cost(adj) < 0 {
+			continue
+		}
+		if field.Cost(adj) < cost {
+			cost = field.Cost(adj)
+			dist = field.Steps(adj)
+			out = mgl32.Vec2{
+				g.grid.InversePosition(adj.X()),
+				g.grid.InversePosition(adj.Y()),
 			}
+			valid = true
 		}
 	}
+	return
+}
+
+// isWalkable returns true if mobs can stand on the tile at pt.
+func (g *Grid) isWalkable(pt Ivec2) bool {
+	item := g.Get(pt)
+	if item == nil {
+		item = g.GetBg(pt)
+	}
+	return item != nil && item.Passable()
+}
+
+// ClearSpeedModifiers removes all modifiers added by AddSpeedModifier.
+func (g *Grid) ClearSpeedModifiers() {
+	g.modifiers = map[Ivec2]float64{}
+}
+
+// AddSpeedModifier slows down or speeds up mobs at pt. Modifiers do not
+// stack; the one furthest from 1.0 wins.
+func (g *Grid) AddSpeedModifier(pt Ivec2, modifier float64) {
+	if current, ok := g.modifiers[pt]; ok && math.Abs(current-1.0) >= math.Abs(modifier-1.0) {
+		return
+	}
+	g.modifiers[pt] = modifier
+}
+
+// Speed returns the multiplier applied to the speed of mobs at pt.
+func (g *Grid) Speed(pt Ivec2) float64 {
+	var speed = 1.0
+	if item := g.GetBg(pt); item != nil {
+		speed *= item.Speed()
+	}
+	if modifier, ok := g.modifiers[pt]; ok {
+		speed *= modifier
+	}
+	return math.Max(speed, MinTerrainSpeed)
+}
+
+// Recovery returns how much fear per second mobs at pt lose.
+func (g *Grid) Recovery(pt Ivec2) float64 {
+	if item := g.GetBg(pt); item != nil {
+		return item.Recovery()
+	}
+	return 0
+}
+
+// stepCost returns the path cost of stepping onto pt, or false if it can't be
+// walked on.
+func (g *Grid) stepCost(pt Ivec2) (int32, bool) {
+	if !g.isWalkable(pt) {
+		return 0, false
+	}
+	cost := BaseStepCost / g.Speed(pt)
+	if multiplier, ok := g.pathCosts[pt]; ok {
+		cost *= multiplier
+	}
+	return int32(math.Max(1, math.Ceil(cost))), true
+}
+
+// ClearPathCosts removes all multipliers added by AddPathCost.
+func (g *Grid) ClearPathCosts() {
+	g.pathCosts = map[Ivec2]float64{}
 }
 
+// AddPathCost makes routes through pt cheaper (below 1) or dearer (above 1)
+// without changing how fast mobs actually walk there. Like speed modifiers,
+// multipliers don't stack and the one furthest from 1 wins.
+func (g *Grid) AddPathCost(pt Ivec2, multiplier float64) {
+	if current, ok := g.pathCosts[pt]; ok && math.Abs(math.Log(current)) >= math.Abs(math.Log(multiplier)) {
+		return
+	}
+	g.pathCosts[pt] = multiplier
+}
+
+// NewPathField returns a distance field leading to target over the current
+// layout. Unlike sinks and targets, it isn't kept up to date.
+func (g *Grid) NewPathField(target Ivec2) *DistanceField {
+	field := NewDistanceField(target, g.Width(), g.Height())
+	field.Calculate(g.getLinked, g.stepCost)
+	return field
+}
+
+// CalculateDistances updates the routes to every sink and target.
 func (g *Grid) CalculateDistances() {
-	var (
-		queue       = []Ivec2{g.sink}
-		dist  int32 = 1
-		item  *GridItem
-	)
-	g.resetDistances()
-	for len(queue) > 0 {
-		pt := queue[0]
-		queue = queue[1:]
-		points := g.getAdjacent(pt)
-		item = g.Get(pt)
-		if item == nil {
-			item = g.GetBg(pt)
-		}
-		dist = item.Distance() + 1
-		for _, adj := range points {
-			item = g.Get(adj)
-			if item == nil {
-				item = g.GetBg(adj)
-			}
-			if item != nil && item.Distance() == -1 && item.Passable() {
-				queue = append(queue, adj)
-				item.SetDistance(dist)
-			}
-		}
+	for _, field := range g.sinks {
+		field.Calculate(g.getLinked, g.stepCost)
+	}
+	for _, field := range g.targets {
+		field.Calculate(g.getLinked, g.stepCost)
 	}
 }
 
@@ -266,6 +635,82 @@ func (g *Grid) GetBg(pt Ivec2) (item *GridItem) {
 	return nil
 }
 
+// IsOpaque returns true if either the placed item or the background tile at
+// pt blocks line of sight. Points outside of the map, including the gaps
+// between floors, are always opaque.
+func (g *Grid) IsOpaque(pt Ivec2) bool {
+	if pt.X() < 0 || pt.Y() < 0 || pt.X() >= g.Width() || pt.Y() >= g.Height() {
+		return true
+	}
+	if g.GetBg(pt) == nil {
+		return true
+	}
+	if item := g.Get(pt); item != nil && item.Opaque() {
+		return true
+	}
+	if item := g.GetBg(pt); item != nil && item.Opaque() {
+		return true
+	}
+	return false
+}
+
+// LineOfSight walks every cell crossed by a line between the centers of from
+// and to, returning false as soon as it crosses an opaque one. The end points
+// and any cells for which ignore returns true are never considered opaque. A
+// line passing exactly through a corner is only blocked if both of the cells
+// touching that corner are opaque.
+func (g *Grid) LineOfSight(from, to Ivec2, ignore func(Ivec2) bool) bool {
+	var (
+		dx     = to.X() - from.X()
+		dy     = to.Y() - from.Y()
+		nx     = absInt32(dx)
+		ny     = absInt32(dy)
+		stepX  = Ivec2{signInt32(dx), 0}
+		stepY  = Ivec2{0, signInt32(dy)}
+		pt     = from
+		ix, iy int32
+	)
+	blocks := func(p Ivec2) bool {
+		if p == to || (ignore != nil && ignore(p)) {
+			return false
+		}
+		return g.IsOpaque(p)
+	}
+	for ix < nx || iy < ny {
+		switch decision := (1+2*ix)*ny - (1+2*iy)*nx; {
+		case decision == 0:
+			if blocks(pt.Plus(stepX)) && blocks(pt.Plus(stepY)) {
+				return false
+			}
+			pt = pt.Plus(stepX).Plus(stepY)
+			ix++
+			iy++
+		case decision < 0:
+			pt = pt.Plus(stepX)
+			ix++
+		default:
+			pt = pt.Plus(stepY)
+			iy++
+		}
+		if blocks(pt) {
+			return false
+		}
+	}
+	return true
+}
+
+// CanSee returns true if the given placement has line of sight to pt. The
+// placement's own tiles never block its view.
+func (g *Grid) CanSee(placement BlockPlacement, pt Ivec2) bool {
+	return g.LineOfSight(placement.Pos, pt, placement.Intersects)
+}
+
+// getLinked returns the points adjacent to point along with any points
+// linked to it by stairs.
+func (g *Grid) getLinked(point Ivec2) []Ivec2 {
+	return append(g.getAdjacent(point), g.stairs[point]...)
+}
+
 func (g *Grid) getAdjacent(point Ivec2) (points []Ivec2) {
 	var adj Ivec2
 	adj = point.Plus(Ivec2{-1, 0})
@@ -0,0 +1,233 @@
+// Copyright 2015 Pikkpoiss
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"../lib/twodee"
+	"github.com/go-gl/mathgl/mgl32"
+	"testing"
+	"time"
+)
+
+// newTestGrid returns an empty grid with passable, transparent ground.
+func newTestGrid(w, h int32) *Grid {
+	g := &Grid{
+		background: twodee.NewGrid(w, h, 1.0),
+		grid:       twodee.NewGrid(w, h, 1.0),
+		modifiers:  map[Ivec2]float64{},
+	}
+	for x := int32(0); x < w; x++ {
+		for y := int32(0); y < h; y++ {
+			g.background.Set(x, y, NewGridItem(true, false, "", nil))
+		}
+	}
+	return g
+}
+
+var lineOfSightTests = []struct {
+	walls    []Ivec2
+	from     Ivec2
+	to       Ivec2
+	expected bool
+}{
+	{nil, Ivec2{0, 0}, Ivec2{5, 3}, true},
+	{[]Ivec2{{2, 0}}, Ivec2{0, 0}, Ivec2{4, 0}, false},
+	{[]Ivec2{{2, 1}}, Ivec2{0, 0}, Ivec2{4, 0}, true},
+	{[]Ivec2{{4, 0}}, Ivec2{0, 0}, Ivec2{4, 0}, true},
+	{[]Ivec2{{1, 0}}, Ivec2{0, 0}, Ivec2{2, 2}, true},
+	{[]Ivec2{{1, 0}, {0, 1}}, Ivec2{0, 0}, Ivec2{2, 2}, false},
+	{[]Ivec2{{2, 2}}, Ivec2{0, 0}, Ivec2{5, 4}, false},
+}
+
+func TestLineOfSight(t *testing.T) {
+	for i, lt := range lineOfSightTests {
+		g := newTestGrid(8, 8)
+		for _, pt := range lt.walls {
+			g.Set(pt, NewGridItem(false, true, "", nil))
+		}
+		if seen := g.LineOfSight(lt.from, lt.to, nil); seen != lt.expected {
+			t.Errorf("Test %v: expected line of sight %v got %v", i, lt.expected, seen)
+		}
+		if seen := g.LineOfSight(lt.to, lt.from, nil); seen != lt.expected {
+			t.Errorf("Test %v: expected reverse line of sight %v got %v", i, lt.expected, seen)
+		}
+	}
+}
+
+func TestCanSeeIgnoresOwnTiles(t *testing.T) {
+	var (
+		g         = newTestGrid(8, 8)
+		placement = BlockPlacement{Pos: Ivec2{3, 3}, Block: &CornerBlock}
+	)
+	if _, ok := g.SetBlock(placement); !ok {
+		t.Fatalf("Could not place block")
+	}
+	if !g.CanSee(placement, Ivec2{6, 3}) {
+		t.Errorf("Expected block to see past its own tiles")
+	}
+	g.Set(Ivec2{5, 3}, NewGridItem(false, true, "", nil))
+	if g.CanSee(placement, Ivec2{6, 3}) {
+		t.Errorf("Expected wall to block line of sight")
+	}
+}
+
+func TestSlowTilesAreAvoided(t *testing.T) {
+	var (
+		g        = newTestGrid(5, 3)
+		start    = mgl32.Vec2{1.5, 1.5}
+		expected = Ivec2{2, 1}
+	)
+	g.AddSink(Ivec2{4, 1})
+	g.CalculateDistances()
+	if next, _, _ := g.GetNextStepToSink(start); g.WorldToGrid(next) != expected {
+		t.Fatalf("Expected step to %v got %v", expected, next)
+	}
+	g.AddSpeedModifier(Ivec2{2, 1}, 0.25)
+	g.CalculateDistances()
+	expected = Ivec2{1, 0}
+	if next, _, _ := g.GetNextStepToSink(start); g.WorldToGrid(next) != expected {
+		t.Fatalf("Expected step around slow tile to %v got %v", expected, next)
+	}
+	if dist := g.Distance(Ivec2{1, 0}); dist != 3 {
+		t.Errorf("Expected 3 steps from detour to sink got %v", dist)
+	}
+}
+
+// lureRow returns path costs of multiplier along row y of a 5 wide grid.
+func lureRow(y int32, multiplier float64) map[Ivec2]float64 {
+	costs := map[Ivec2]float64{}
+	for x := int32(0); x < 5; x++ {
+		costs[Ivec2{x, y}] = multiplier
+	}
+	return costs
+}
+
+func TestLuresBendRoutes(t *testing.T) {
+	var start = mgl32.Vec2{1.5, 1.5}
+	for _, lt := range []struct {
+		name     string
+		costs    []map[Ivec2]float64
+		expected Ivec2
+	}{
+		{"no lures", nil, Ivec2{2, 1}},
+		{"repelling tiles", []map[Ivec2]float64{{Ivec2{2, 1}: 4.0, Ivec2{2, 0}: 4.0}}, Ivec2{1, 2}},
+		{"lure below", []map[Ivec2]float64{lureRow(2, 0.3)}, Ivec2{1, 2}},
+		{"stronger lure below", []map[Ivec2]float64{lureRow(0, 0.5), lureRow(2, 0.3)}, Ivec2{1, 2}},
+		{"stronger lure above", []map[Ivec2]float64{lureRow(0, 0.3), lureRow(2, 0.5)}, Ivec2{1, 0}},
+	} {
+		g := newTestGrid(5, 3)
+		g.ClearPathCosts()
+		g.AddSink(Ivec2{4, 1})
+		for _, costs := range lt.costs {
+			for pt, multiplier := range costs {
+				g.AddPathCost(pt, multiplier)
+			}
+		}
+		g.CalculateDistances()
+		if next, _, _ := g.GetNextStepToSink(start); g.WorldToGrid(next) != lt.expected {
+			t.Errorf("Expected %v to step to %v, got %v", lt.name, lt.expected, next)
+		}
+		if speed := g.Speed(Ivec2{1, 2}); speed != 1 {
+			t.Errorf("Expected %v not to change speed, got %v", lt.name, speed)
+		}
+	}
+}
+
+func TestDisabledLuresStopPulling(t *testing.T) {
+	var (
+		level = newTestLevel(newTestGrid(8, 8))
+		key   = addTestBlock(t, level, &LureBlock, Ivec2{4, 4})
+		pt    = Ivec2{4, 5}
+	)
+	level.Grid.ClearPathCosts()
+	level.updateTerrain()
+	if _, ok := level.Grid.pathCosts[pt]; !ok {
+		t.Fatalf("Expected the lure to pull mobs through %v", pt)
+	}
+	addTestMobs(level, CalmFear, mgl32.Vec2{4.5, 3.5})
+	level.Mobs[0].Kind = &SkepticKind
+	level.updateSabotage(time.Second)
+	if !level.terrainStale {
+		t.Fatalf("Expected disabling the lure to mark the terrain stale")
+	}
+	level.updateTerrain()
+	if _, ok := level.Grid.pathCosts[pt]; ok {
+		t.Errorf("Expected a disabled lure not to pull mobs")
+	}
+	level.Mobs[0].Enabled = false
+	level.updateBlocks(level.blocks[key].DisabledFor)
+	if !level.terrainStale {
+		t.Fatalf("Expected the lure waking up to mark the terrain stale")
+	}
+	level.updateTerrain()
+	if _, ok := level.Grid.pathCosts[pt]; !ok {
+		t.Errorf("Expected the lure to pull mobs again once it works")
+	}
+}
+
+func newTestFloor(name string, w, h int32, stairs Ivec2) *TiledFloor {
+	floor := &TiledFloor{
+		Name:       name,
+		Background: twodee.NewGrid(w, h, 1.0),
+		Markers:    []MapMarker{{Kind: MarkerStairs, Name: "stairs", Pos: stairs}},
+	}
+	for x := int32(0); x < w; x++ {
+		for y := int32(0); y < h; y++ {
+			floor.Background.Set(x, y, NewGridItem(true, false, "", nil))
+		}
+	}
+	return floor
+}
+
+func TestStairsConnectFloors(t *testing.T) {
+	g, err := newGrid(&TiledMap{Floors: []*TiledFloor{
+		newTestFloor("Ground", 4, 3, Ivec2{3, 0}),
+		newTestFloor("Attic", 3, 2, Ivec2{0, 1}),
+	}})
+	if err != nil {
+		t.Fatalf("Could not compose floors: %v", err)
+	}
+	if g.Width() != 4+FloorGap+3 || g.ViewWidth() != 4 || g.ViewHeight() != 3 {
+		t.Fatalf("Unexpected size %vx%v, view %vx%v", g.Width(), g.Height(), g.ViewWidth(), g.ViewHeight())
+	}
+	var upstairs = Ivec2{4 + FloorGap, 1}
+	if !g.IsStairs(Ivec2{3, 0}, upstairs) || !g.IsStairs(upstairs, Ivec2{3, 0}) {
+		t.Errorf("Expected stairs between floors")
+	}
+	if floor := g.FloorAt(Ivec2{4, 0}); floor != -1 {
+		t.Errorf("Expected gap between floors, got floor %v", floor)
+	}
+	g.AddSink(Ivec2{6, 0})
+	g.CalculateDistances()
+	if steps := g.Distance(Ivec2{0, 2}); steps < 0 {
+		t.Fatalf("Expected sink upstairs to be reachable")
+	}
+	// Walk from the far corner of the ground floor and check we go upstairs.
+	var (
+		pos     = mgl32.Vec2{0.5, 2.5}
+		visited = map[int]bool{}
+	)
+	for i := 0; i < 20; i++ {
+		next, _, ok := g.GetNextStepToSink(pos)
+		if !ok {
+			break
+		}
+		pos = next
+		visited[g.FloorAt(g.WorldToGrid(pos))] = true
+	}
+	if !visited[1] || visited[-1] {
+		t.Errorf("Expected path to use the stairs, visited floors %v", visited)
+	}
+}
@@ -22,14 +22,18 @@ import (
 
 type GridItem struct {
 	passable  bool
-	distance  int32
+	opaque    bool
+	speed     float64
+	recovery  float64
+	region    string
+	theme     string
 	frame     string
 	animation *twodee.FrameAnimation
 	frames    BlockAnimations
 	state     BlockState
 }
 
-func NewGridItem(passable bool, frame string, frames BlockAnimations) *GridItem {
+func NewGridItem(passable, opaque bool, frame string, frames BlockAnimations) *GridItem {
 	var (
 		animation *twodee.FrameAnimation
 		state     = BlockNormal
@@ -42,7 +46,8 @@ func NewGridItem(passable bool, frame string, frames BlockAnimations) *GridItem
 	}
 	return &GridItem{
 		passable:  passable,
-		distance:  -1,
+		opaque:    opaque,
+		speed:     1.0,
 		frame:     frame,
 		frames:    frames,
 		animation: animation,
@@ -59,6 +64,11 @@ func (i *GridItem) SetState(state BlockState) {
 	}
 }
 
+// State returns the block state the item is currently animating.
+func (i *GridItem) State() BlockState {
+	return i.state
+}
+
 func (i *GridItem) Update(elapsed time.Duration) {
 	if i.animation != nil {
 		i.animation.Update(elapsed)
@@ -76,14 +86,27 @@ func (i *GridItem) Passable() bool {
 	return i.passable
 }
 
+// Opaque returns true if this item blocks the line of sight of blocks.
 func (i *GridItem) Opaque() bool {
-	return i.passable
+	return i.opaque
+}
+
+// Speed returns the multiplier applied to the speed of mobs on this item.
+func (i *GridItem) Speed() float64 {
+	return i.speed
+}
+
+// Recovery returns how much fear per second mobs lose while on this item.
+func (i *GridItem) Recovery() float64 {
+	return i.recovery
 }
 
-func (i *GridItem) SetDistance(dist int32) {
-	i.distance = dist
+// Region returns the name of the region this item belongs to, if any.
+func (i *GridItem) Region() string {
+	return i.region
 }
 
-func (i *GridItem) Distance() int32 {
-	return i.distance
+// Theme returns the room theme set on this item by the map, if any.
+func (i *GridItem) Theme() string {
+	return i.theme
 }
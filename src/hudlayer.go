@@ -20,7 +20,9 @@ import (
 	"github.com/go-gl/mathgl/mgl32"
 	"image/color"
 	"io/ioutil"
+	"math"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -29,7 +31,6 @@ type HudItem struct {
 	Enabled     bool
 	Highlighted bool
 	Block       *Block
-	KeyText     string
 }
 
 type HudLayer struct {
@@ -45,8 +46,19 @@ type HudLayer struct {
 	textCache      map[string]*twodee.TextCache
 	items          []HudItem
 	textScale      float32
+	subs           *Subscriptions
+	notice         string
+	noticeTimer    time.Duration
+	geldDelta      int
+	geldDeltaTimer time.Duration
 }
 
+const (
+	NoticeDuration    = 4 * time.Second
+	GeldDeltaDuration = 1 * time.Second
+	ToolbarIconSize   = 1.5
+)
+
 func NewHudLayer(state *State, grid *Grid, app *Application) (layer *HudLayer, err error) {
 	var (
 		regFont        *twodee.FontFace
@@ -61,7 +73,7 @@ func NewHudLayer(state *State, grid *Grid, app *Application) (layer *HudLayer, e
 		textSize               = 32.0
 	)
 	if camera, err = twodee.NewCamera(
-		twodee.Rect(0, 0, float32(grid.Width()), float32(grid.Height())),
+		twodee.Rect(0, 0, float32(grid.ViewWidth()), float32(grid.ViewHeight())),
 		twodee.Rect(0, 0, ScreenWidth, ScreenHeight),
 	); err != nil {
 		return
@@ -80,12 +92,41 @@ func NewHudLayer(state *State, grid *Grid, app *Application) (layer *HudLayer, e
 		app:       app,
 		textCache: map[string]*twodee.TextCache{},
 		textScale: textScale,
+		subs:      NewSubscriptions(app.GameEventHandler),
 	}
+	layer.subs.Add(AchievementUnlocked, layer.onAchievementUnlocked)
+	layer.subs.Add(GeldChanged, layer.onGeldChanged)
+	layer.subs.Add(SaveFailed, layer.onSaveFailed)
 	err = layer.Reset()
 	return
 }
 
+func (h *HudLayer) onAchievementUnlocked(e twodee.GETyper) {
+	if evt, ok := e.(*AchievementEvent); ok {
+		h.notice = fmt.Sprintf("Achievement: %v", evt.Achievement.Title)
+		h.noticeTimer = NoticeDuration
+	}
+}
+
+func (h *HudLayer) onSaveFailed(e twodee.GETyper) {
+	if evt, ok := e.(*SaveFailedEvent); ok {
+		h.notice = fmt.Sprintf("Could not save %v: %v", evt.What, evt.Err)
+		h.noticeTimer = NoticeDuration
+	}
+}
+
+func (h *HudLayer) onGeldChanged(e twodee.GETyper) {
+	if evt, ok := e.(*AmountEvent); ok {
+		if h.geldDeltaTimer <= 0 {
+			h.geldDelta = 0
+		}
+		h.geldDelta += evt.Delta
+		h.geldDeltaTimer = GeldDeltaDuration
+	}
+}
+
 func (h *HudLayer) Delete() {
+	h.subs.Delete()
 	h.textRenderer.Delete()
 	h.spriteRenderer.Delete()
 }
@@ -140,6 +181,10 @@ func (h *HudLayer) renderText() {
 			"RATING",
 			strconv.Itoa(h.state.Geld),
 			"GELD",
+			strconv.Itoa(h.state.Handymen + h.state.Minions),
+			"STAFF",
+			strconv.Itoa(int(h.app.Avatar().Energy)),
+			"ENERGY",
 		}
 	)
 	h.textRenderer.Bind()
@@ -165,8 +210,69 @@ func (h *HudLayer) renderText() {
 		}
 	}
 
+	// Render recent change in Geld underneath the amount.
+	if h.geldDeltaTimer > 0 && h.geldDelta != 0 {
+		texture = h.cacheText("geldDelta", h.pixelFont, fmt.Sprintf("%+d", h.geldDelta))
+		if texture != nil {
+			texHeight = float32(texture.Height) * h.textScale
+			texWidth = float32(texture.Width) * h.textScale
+			h.textRenderer.Draw(texture, h.camera.WorldBounds.Max.X()-texWidth-1, yText-2*texHeight, h.textScale)
+		}
+	}
+
+	// Render the level's objectives along the top.
+	for i, objective := range h.app.Objectives() {
+		texture = h.cacheText(fmt.Sprintf("objective%v", i), h.pixelFont, h.app.ObjectiveText(objective))
+		if texture != nil {
+			texHeight = float32(texture.Height) * h.textScale
+			texWidth = float32(texture.Width) * h.textScale
+			h.textRenderer.Draw(texture, (h.camera.WorldBounds.Max.X()-texWidth)/2, yText-float32(i+1)*texHeight, h.textScale)
+		}
+	}
+
+	// Render notices such as unlocked achievements along the bottom.
+	if h.noticeTimer > 0 {
+		texture = h.cacheText("notice", h.regFont, h.notice)
+		if texture != nil {
+			texWidth = float32(texture.Width) * h.textScale
+			h.textRenderer.Draw(texture, (h.camera.WorldBounds.Max.X()-texWidth)/2, 0.5, h.textScale)
+		}
+	}
+
+	// Render stats for the room under the cursor in the bottom right.
+	if room := h.app.RoomUnderCursor(); room != nil && !h.toolbarHighlighted() {
+		texture = h.cacheText("room", h.pixelFont, roomText(room))
+		if texture != nil {
+			texWidth = float32(texture.Width) * h.textScale
+			h.textRenderer.Draw(texture, h.camera.WorldBounds.Max.X()-texWidth-0.5, 0.5, h.textScale)
+		}
+	}
+
+	// Render the combos the block being placed would form.
+	if combos := h.app.ComboPreview(); len(combos) > 0 {
+		texture = h.cacheText("combos", h.pixelFont, "Combo: "+strings.Join(combos, ", "))
+		if texture != nil {
+			texWidth = float32(texture.Width) * h.textScale
+			h.textRenderer.Draw(texture, (h.camera.WorldBounds.Max.X()-texWidth)/2, 1.5, h.textScale)
+		}
+	}
+
+	// Render the avatar's abilities above the floor name.
+	texture = h.cacheText("abilities", h.pixelFont, abilitiesText(h.app.Avatar(), h.app.Input))
+	if texture != nil {
+		h.textRenderer.Draw(texture, 0.5, 1.5, h.textScale)
+	}
+
+	// Render the name of the floor in view in the bottom left.
+	if name := h.app.FloorName(); name != "" {
+		texture = h.cacheText("floor", h.pixelFont, name)
+		if texture != nil {
+			h.textRenderer.Draw(texture, 0.5, 0.5, h.textScale)
+		}
+	}
+
 	for i, item := range h.items {
-		texture = h.cacheText(fmt.Sprintf("key%v", i), h.pixelFont, item.KeyText)
+		texture = h.cacheText(fmt.Sprintf("key%v", i), h.pixelFont, h.app.Input.KeyName(item.Block.Action))
 		if texture != nil {
 			h.textRenderer.Draw(texture, item.HitBox.Min.X() + 0.1, item.HitBox.Min.Y(), h.textScale)
 		}
@@ -179,6 +285,37 @@ func (h *HudLayer) renderText() {
 	h.textRenderer.Unbind()
 }
 
+func (h *HudLayer) toolbarHighlighted() bool {
+	for _, item := range h.items {
+		if item.Highlighted {
+			return true
+		}
+	}
+	return false
+}
+
+func abilitiesText(avatar *Avatar, input *InputMap) string {
+	var parts []string
+	for _, ability := range Abilities {
+		status := "ready"
+		if left := avatar.Cooldown(ability); left > 0 {
+			status = fmt.Sprintf("%.0fs", math.Ceil(left.Seconds()))
+		} else if !avatar.Ready(ability) {
+			status = "low energy"
+		}
+		parts = append(parts, fmt.Sprintf("%v [%v] %v", ability.Name, input.KeyName(ability.Action), status))
+	}
+	return strings.Join(parts, "  ")
+}
+
+func roomText(room *Room) string {
+	text := fmt.Sprintf("%v: %v visitors, %v blocks", room.Name, room.Occupancy, len(room.Blocks))
+	if room.Theme != "" {
+		text += fmt.Sprintf(", %v x%.2f", room.Theme, room.FearMultiplier())
+	}
+	return text
+}
+
 func (h *HudLayer) Render() {
 	var configs = []twodee.SpriteConfig{}
 
@@ -196,7 +333,51 @@ func (h *HudLayer) Render() {
 	// Put text on top
 	if h.state.SplashState == SplashDisabled {
 		h.renderText()
+	} else if h.state.SplashState == SplashWin && h.state.Result != nil {
+		h.renderResult(h.state.Result)
+	}
+}
+
+// renderResult shows the medals and score for the level just won across the
+// bottom of the win screen, under any notice such as medals failing to save.
+func (h *HudLayer) renderResult(result *LevelResult) {
+	var (
+		texture  *twodee.Texture
+		texWidth float32
+		yText    float32 = 1
+		lines            = result.Level.Par.Text(result.Score)
+	)
+	h.textRenderer.Bind()
+	if h.noticeTimer > 0 {
+		texture = h.cacheText("notice", h.regFont, h.notice)
+		if texture != nil {
+			texWidth = float32(texture.Width) * h.textScale
+			h.textRenderer.Draw(texture, (h.camera.WorldBounds.Max.X()-texWidth)/2, yText, h.textScale)
+			yText += float32(texture.Height) * h.textScale
+		}
+	}
+	for i := len(lines) - 1; i >= 0; i-- {
+		texture = h.cacheText(fmt.Sprintf("result%v", i), h.pixelFont, lines[i])
+		if texture != nil {
+			texWidth = float32(texture.Width) * h.textScale
+			h.textRenderer.Draw(texture, (h.camera.WorldBounds.Max.X()-texWidth)/2, yText, h.textScale)
+			yText += float32(texture.Height) * h.textScale
+		}
+	}
+	texture = h.cacheText("medals", h.regFont, medalsText(result))
+	if texture != nil {
+		texWidth = float32(texture.Width) * h.textScale
+		h.textRenderer.Draw(texture, (h.camera.WorldBounds.Max.X()-texWidth)/2, yText, h.textScale)
+	}
+	h.textRenderer.Unbind()
+}
+
+func medalsText(result *LevelResult) string {
+	text := fmt.Sprintf("%v: %v/%v medals for %v", result.Level.Name, result.Medals, MaxMedals, result.Profile)
+	if result.Best > result.Medals {
+		text += fmt.Sprintf(" (best %v)", result.Best)
 	}
+	return text
 }
 
 func (h *HudLayer) HandleEvent(evt twodee.Event) bool {
@@ -205,11 +386,7 @@ func (h *HudLayer) HandleEvent(evt twodee.Event) bool {
 		if event.Type == twodee.Press && event.Button == twodee.MouseButtonLeft {
 			for _, item := range h.items {
 				if item.Highlighted {
-					if item.KeyText == "d" { // ugh
-						h.app.SetUiState(NewDeleteUiState())
-					} else {
-						h.app.SetUiState(NewBlockUiState(item.Block))
-					}
+					h.app.SetUiState(NewToolbarUiState(item.Block))
 					return false
 				}
 			}
@@ -222,13 +399,15 @@ func (h *HudLayer) makeItems() {
 	var (
 		yMax      = h.camera.WorldBounds.Max.Y()
 		block     *Block
-		boxHeight float32 = 2
+		boxHeight float32 = 1.75
 		boxWidth  float32 = 2
-		boxOffset float32 = 4
+		boxOffset float32 = 3
 		bottom    float32
 		top       float32
 		i         int
 	)
+	// The column has to stay clear of the abilities and floor name, which
+	// take the bottom 2.5 units.
 	h.items = make([]HudItem, len(HudBlocks))
 	for i, block = range HudBlocks {
 		top = yMax - (boxHeight*float32(i) + boxOffset)
@@ -237,7 +416,6 @@ func (h *HudLayer) makeItems() {
 		h.items[i].Highlighted = false
 		h.items[i].HitBox = twodee.Rect(0, bottom, boxWidth, top)
 		h.items[i].Block = block
-		h.items[i].KeyText = block.Key
 	}
 }
 
@@ -260,6 +438,12 @@ func (h *HudLayer) Reset() (err error) {
 
 func (h *HudLayer) Update(elapsed time.Duration) {
 	var overlaps bool
+	if h.noticeTimer > 0 {
+		h.noticeTimer -= elapsed
+	}
+	if h.geldDeltaTimer > 0 {
+		h.geldDeltaTimer -= elapsed
+	}
 	for i, item := range h.items {
 		overlaps = item.HitBox.ContainsPoint(twodee.Point{h.state.MousePos})
 		h.items[i].Highlighted = overlaps
@@ -294,13 +478,15 @@ func (h *HudLayer) loadSpritesheet() (err error) {
 func (h *HudLayer) toolbarSpriteConfig(sheet *twodee.Spritesheet, name string, y float32) twodee.SpriteConfig {
 	var frame *twodee.SpritesheetFrame
 	frame = sheet.GetFrame(name)
-	xPosition := (frame.Width / 2.0) + 1.2
-	yPosition := y + (frame.Height / 2.0) // Bottom aligned
+	// Shrink sprites which are larger than an icon to fit the toolbar.
+	scale := float32(math.Min(1.0, ToolbarIconSize/math.Max(float64(frame.Width), float64(frame.Height))))
+	xPosition := (frame.Width * scale / 2.0) + 1.2
+	yPosition := y + (frame.Height * scale / 2.0) // Bottom aligned
 	return twodee.SpriteConfig{
 		View: twodee.ModelViewConfig{
 			xPosition, yPosition, 0,
 			0, 0, 0,
-			1.0, 1.0, 1.0,
+			scale, scale, 1.0,
 		},
 		Frame: frame.Frame,
 	}
@@ -0,0 +1,39 @@
+// Copyright 2015 Pikkpoiss
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"../lib/twodee"
+	"testing"
+)
+
+func TestToolbarClearsBottomText(t *testing.T) {
+	var h = &HudLayer{
+		camera: &twodee.Camera{WorldBounds: twodee.Rect(0, 0, 32, 20)},
+	}
+	h.makeItems()
+	for i, item := range h.items {
+		// The abilities and floor name are one unit tall each, from 0.5.
+		if bottom := item.HitBox.Min.Y(); bottom < 2.5 {
+			t.Errorf("Expected %v to sit above the bottom text, starts at %v", item.Block.Title, bottom)
+		}
+		if i > 0 && item.HitBox.Max.Y() > h.items[i-1].HitBox.Min.Y() {
+			t.Errorf("Expected %v not to overlap %v", item.Block.Title, h.items[i-1].Block.Title)
+		}
+		if height := item.HitBox.Max.Y() - item.HitBox.Min.Y(); height < ToolbarIconSize {
+			t.Errorf("Expected room for a %v icon, got %v", ToolbarIconSize, height)
+		}
+	}
+}
@@ -0,0 +1,294 @@
+// Copyright 2015 Pikkpoiss
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"../lib/twodee"
+	"fmt"
+	"strings"
+)
+
+type InputAction int32
+
+const (
+	ActionNone InputAction = iota
+	ActionBlock1
+	ActionBlock2
+	ActionBlock3
+	ActionBlock4
+	ActionBlock5
+	ActionBlock6
+	ActionBlock7
+	ActionBlock8
+	ActionBlock9
+	ActionNormalMode
+	ActionDeleteMode
+	ActionRotate
+	ActionToggleDoor
+	ActionRepair
+	ActionHireHandyman
+	ActionHireMinion
+	ActionAvatarUp
+	ActionAvatarDown
+	ActionAvatarLeft
+	ActionAvatarRight
+	ActionBoo
+	ActionChill
+	ActionPhase
+	ActionFloorUp
+	ActionFloorDown
+	ActionToggleMusic
+	ActionMenu
+	ActionMenuUp
+	ActionMenuDown
+	ActionMenuSelect
+	ActionAdvance
+	NumInputActions
+)
+
+const KeyBindingsFile = "keys.json"
+
+type inputActionInfo struct {
+	Name  string // Used in the key bindings file.
+	Label string // Shown on the rebinding screen.
+	Key   twodee.KeyCode
+}
+
+var inputActions = map[InputAction]inputActionInfo{
+	ActionBlock1:       {"Block1", "Block 1", twodee.Key1},
+	ActionBlock2:       {"Block2", "Block 2", twodee.Key2},
+	ActionBlock3:       {"Block3", "Block 3", twodee.Key3},
+	ActionBlock4:       {"Block4", "Block 4", twodee.Key4},
+	ActionBlock5:       {"Block5", "Block 5", twodee.Key5},
+	ActionBlock6:       {"Block6", "Block 6", twodee.Key6},
+	ActionBlock7:       {"Block7", "Block 7", twodee.Key7},
+	ActionBlock8:       {"Block8", "Block 8", twodee.Key8},
+	ActionBlock9:       {"Block9", "Block 9", twodee.Key9},
+	ActionNormalMode:   {"NormalMode", "Put down block", twodee.Key0},
+	ActionDeleteMode:   {"DeleteMode", "Delete blocks", twodee.KeyD},
+	ActionRotate:       {"Rotate", "Rotate block", twodee.KeyR},
+	ActionToggleDoor:   {"ToggleDoor", "Open/close door", twodee.KeyE},
+	ActionRepair:       {"Repair", "Repair block", twodee.KeyF},
+	ActionHireHandyman: {"HireHandyman", "Hire handyman", twodee.KeyH},
+	ActionHireMinion:   {"HireMinion", "Hire minion", twodee.KeyN},
+	ActionAvatarUp:     {"AvatarUp", "Monster up", twodee.KeyI},
+	ActionAvatarDown:   {"AvatarDown", "Monster down", twodee.KeyK},
+	ActionAvatarLeft:   {"AvatarLeft", "Monster left", twodee.KeyJ},
+	ActionAvatarRight:  {"AvatarRight", "Monster right", twodee.KeyL},
+	ActionBoo:          {"Boo", "Boo!", twodee.KeyQ},
+	ActionChill:        {"Chill", "Chill", twodee.KeyW},
+	ActionPhase:        {"Phase", "Phase through walls", twodee.KeyT},
+	ActionFloorUp:      {"FloorUp", "Floor up", twodee.KeyPageUp},
+	ActionFloorDown:    {"FloorDown", "Floor down", twodee.KeyPageDown},
+	ActionToggleMusic:  {"ToggleMusic", "Toggle music", twodee.KeyM},
+	ActionMenu:         {"Menu", "Menu", twodee.KeyEscape},
+	ActionMenuUp:       {"MenuUp", "Menu up", twodee.KeyUp},
+	ActionMenuDown:     {"MenuDown", "Menu down", twodee.KeyDown},
+	ActionMenuSelect:   {"MenuSelect", "Menu select", twodee.KeyEnter},
+	ActionAdvance:      {"Advance", "Skip screen", twodee.KeySpace},
+}
+
+var keyNames = map[twodee.KeyCode]string{
+	twodee.Key0: "0", twodee.Key1: "1", twodee.Key2: "2", twodee.Key3: "3",
+	twodee.Key4: "4", twodee.Key5: "5", twodee.Key6: "6", twodee.Key7: "7",
+	twodee.Key8: "8", twodee.Key9: "9",
+	twodee.KeyA: "a", twodee.KeyB: "b", twodee.KeyC: "c", twodee.KeyD: "d",
+	twodee.KeyE: "e", twodee.KeyF: "f", twodee.KeyG: "g", twodee.KeyH: "h",
+	twodee.KeyI: "i", twodee.KeyJ: "j", twodee.KeyK: "k", twodee.KeyL: "l",
+	twodee.KeyM: "m", twodee.KeyN: "n", twodee.KeyO: "o", twodee.KeyP: "p",
+	twodee.KeyQ: "q", twodee.KeyR: "r", twodee.KeyS: "s", twodee.KeyT: "t",
+	twodee.KeyU: "u", twodee.KeyV: "v", twodee.KeyW: "w", twodee.KeyX: "x",
+	twodee.KeyY: "y", twodee.KeyZ: "z",
+	twodee.KeySpace:      "Space",
+	twodee.KeyApostrophe: "'",
+	twodee.KeyComma:      ",",
+	twodee.KeyMinus:      "-",
+	twodee.KeyPeriod:     ".",
+	twodee.KeySlash:      "/",
+	twodee.KeySemicolon:  ";",
+	twodee.KeyEqual:      "=",
+	twodee.KeyEscape:     "Escape",
+	twodee.KeyEnter:      "Enter",
+	twodee.KeyTab:        "Tab",
+	twodee.KeyBackspace:  "Backspace",
+	twodee.KeyRight:      "Right",
+	twodee.KeyLeft:       "Left",
+	twodee.KeyDown:       "Down",
+	twodee.KeyUp:         "Up",
+	twodee.KeyPageUp:     "PageUp",
+	twodee.KeyPageDown:   "PageDown",
+}
+
+func KeyName(code twodee.KeyCode) string {
+	if name, ok := keyNames[code]; ok {
+		return name
+	}
+	return fmt.Sprintf("#%v", int(code))
+}
+
+func ParseKeyName(name string) (twodee.KeyCode, bool) {
+	for code, n := range keyNames {
+		if strings.EqualFold(n, name) {
+			return code, true
+		}
+	}
+	return 0, false
+}
+
+// InputMap binds each action to a single key.
+type InputMap struct {
+	keys    map[InputAction]twodee.KeyCode
+	actions map[twodee.KeyCode]InputAction
+	path    string
+}
+
+func NewInputMap() *InputMap {
+	m := &InputMap{}
+	m.ResetDefaults()
+	return m
+}
+
+// LoadInputMap starts with the default bindings and applies any saved at
+// path, which maps action names to key names. If the file is invalid the
+// defaults are returned along with the error.
+func LoadInputMap(path string) (m *InputMap, err error) {
+	var (
+		saved  = map[string]string{}
+		code   twodee.KeyCode
+		action InputAction
+		ok     bool
+	)
+	m = NewInputMap()
+	m.path = path
+	defer func() {
+		if err != nil {
+			m.ResetDefaults()
+		}
+	}()
+	if err = LoadJSON(path, &saved); err != nil {
+		return
+	}
+	for name, key := range saved {
+		if action, ok = ActionByName(name); !ok {
+			err = fmt.Errorf("Unknown action %v in %v", name, path)
+			return
+		}
+		if code, ok = ParseKeyName(key); !ok {
+			err = fmt.Errorf("Unknown key %v in %v", key, path)
+			return
+		}
+		m.keys[action] = code
+	}
+	m.reindex()
+	if conflicts := m.Conflicts(); len(conflicts) > 0 {
+		err = fmt.Errorf("%v is bound to more than one action in %v", KeyName(m.keys[conflicts[0]]), path)
+	}
+	return
+}
+
+func ActionByName(name string) (InputAction, bool) {
+	for action, info := range inputActions {
+		if info.Name == name {
+			return action, true
+		}
+	}
+	return ActionNone, false
+}
+
+func (m *InputMap) ResetDefaults() {
+	m.keys = map[InputAction]twodee.KeyCode{}
+	for action, info := range inputActions {
+		m.keys[action] = info.Key
+	}
+	m.reindex()
+}
+
+func (m *InputMap) reindex() {
+	m.actions = map[twodee.KeyCode]InputAction{}
+	for action, key := range m.keys {
+		m.actions[key] = action
+	}
+}
+
+// Action returns the action bound to the key in evt, or ActionNone if evt
+// isn't a key press or the key is unbound.
+func (m *InputMap) Action(evt twodee.Event) InputAction {
+	if event, ok := evt.(*twodee.KeyEvent); ok && event.Type == twodee.Press {
+		return m.actions[event.Code]
+	}
+	return ActionNone
+}
+
+// Held returns the action for a key event along with whether the key went
+// down or came up, for actions which last as long as the key is held.
+func (m *InputMap) Held(evt twodee.Event) (action InputAction, down bool) {
+	if event, ok := evt.(*twodee.KeyEvent); ok {
+		switch event.Type {
+		case twodee.Press:
+			return m.actions[event.Code], true
+		case twodee.Release:
+			return m.actions[event.Code], false
+		}
+	}
+	return ActionNone, false
+}
+
+func (m *InputMap) Key(action InputAction) twodee.KeyCode {
+	return m.keys[action]
+}
+
+func (m *InputMap) KeyName(action InputAction) string {
+	return KeyName(m.keys[action])
+}
+
+// Bind assigns key to action. If another action already uses key nothing
+// changes and that action is returned so the conflict can be reported.
+func (m *InputMap) Bind(action InputAction, key twodee.KeyCode) (conflict InputAction, ok bool) {
+	if other, bound := m.actions[key]; bound && other != action {
+		return other, false
+	}
+	delete(m.actions, m.keys[action])
+	m.keys[action] = key
+	m.actions[key] = action
+	return ActionNone, true
+}
+
+// Conflicts returns every action which shares its key with another.
+func (m *InputMap) Conflicts() (conflicts []InputAction) {
+	var seen = map[twodee.KeyCode]InputAction{}
+	for action := ActionNone + 1; action < NumInputActions; action++ {
+		key := m.keys[action]
+		if other, ok := seen[key]; ok {
+			conflicts = append(conflicts, other, action)
+		}
+		seen[key] = action
+	}
+	return
+}
+
+func (m *InputMap) Save() error {
+	var saved = map[string]string{}
+	if m.path == "" {
+		return nil
+	}
+	for action, key := range m.keys {
+		saved[inputActions[action].Name] = KeyName(key)
+	}
+	return SaveJSON(m.path, saved)
+}
+
+func ActionLabel(action InputAction) string {
+	return inputActions[action].Label
+}
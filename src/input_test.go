@@ -0,0 +1,85 @@
+// Copyright 2015 Pikkpoiss
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"../lib/twodee"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestBindRefusesConflicts(t *testing.T) {
+	var m = NewInputMap()
+	if conflict, ok := m.Bind(ActionRotate, m.Key(ActionRepair)); ok || conflict != ActionRepair {
+		t.Fatalf("Expected a conflict with Repair, got %v %v", conflict, ok)
+	}
+	if m.Key(ActionRotate) != twodee.KeyR {
+		t.Errorf("Expected Rotate to keep its key after a conflict")
+	}
+	if _, ok := m.Bind(ActionRotate, twodee.KeyZ); !ok {
+		t.Fatalf("Expected Z to be free")
+	}
+	if m.Action(&twodee.KeyEvent{Type: twodee.Press, Code: twodee.KeyR}) != ActionNone {
+		t.Errorf("Expected the old key to be unbound")
+	}
+	if m.Action(&twodee.KeyEvent{Type: twodee.Press, Code: twodee.KeyZ}) != ActionRotate {
+		t.Errorf("Expected Z to rotate")
+	}
+	if conflicts := m.Conflicts(); len(conflicts) > 0 {
+		t.Errorf("Expected no conflicts, got %v", conflicts)
+	}
+}
+
+func writeKeys(t *testing.T, contents string) string {
+	file, err := ioutil.TempFile("", "keys")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(contents)
+	file.Close()
+	return file.Name()
+}
+
+func TestLoadInputMap(t *testing.T) {
+	var path = writeKeys(t, `{"Rotate": "z", "Menu": "Tab"}`)
+	defer os.Remove(path)
+	m, err := LoadInputMap(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Key(ActionRotate) != twodee.KeyZ || m.Key(ActionMenu) != twodee.KeyTab {
+		t.Errorf("Expected saved keys, got %v and %v", m.KeyName(ActionRotate), m.KeyName(ActionMenu))
+	}
+}
+
+func TestLoadInputMapFallsBack(t *testing.T) {
+	for _, contents := range []string{
+		`{"Rotate": "d"}`,
+		`{"Levitate": "z"}`,
+		`{"Rotate": "NoSuchKey"}`,
+		`{"Rotate": `,
+	} {
+		path := writeKeys(t, contents)
+		m, err := LoadInputMap(path)
+		os.Remove(path)
+		if err == nil {
+			t.Errorf("Expected an error loading %v", contents)
+		}
+		if m.Key(ActionRotate) != twodee.KeyR || m.Key(ActionDeleteMode) != twodee.KeyD {
+			t.Errorf("Expected default keys after loading %v", contents)
+		}
+	}
+}
@@ -18,6 +18,7 @@ import (
 	"../lib/twodee"
 	"github.com/go-gl/mathgl/mgl32"
 	"math"
+	"math/rand"
 	"sort"
 	"time"
 )
@@ -26,6 +27,7 @@ const (
 	FAIL_RATING  = 1
 	WIN_RATING   = 8
 	WIN_DURATION = 5 * time.Second
+	DeathPenalty = 1.0 // Taken off every fear the rating remembers.
 )
 
 type SpawnZone struct {
@@ -63,48 +65,97 @@ type Level struct {
 	App              *Application
 	Camera           *twodee.Camera
 	Grid             *Grid
+	Floor            int
 	State            *State
 	Mobs             []Mob
 	Decals           []*Decal
+	Ghosts           []*Ghost
+	Minions          []*Minion
+	Avatar           *Avatar
+	ComboPreview     []string // Names of the combos the highlighted block would form.
 	ActiveMobCount   int
 	ActiveDecalCount int
 	Highlights       []Highlight
 	entries          []SpawnZone
-	exit             SpawnZone
+	exits            []Exit
 	blocks           map[Ivec2]BlockPlacement
 	fearBuffer       *CircularBuffer
 	highlighted      *BlockPlacement
 	deleteable       *BlockPlacement
 	gameEventHandler *twodee.GameEventHandler
-	durAtWinRating   time.Duration
+	Def              *LevelDef
+	Objectives       []*Objective
+	playTime         time.Duration
+	over             bool // PlayerWon or PlayerLost has been sent.
+	stats            *StatsTracker
+	nextMobId        int
+	rooms            *Rooms
+	tour             []Ivec2
+	sheet            *twodee.Spritesheet
+	combos           *ComboCatalog
+	activeCombos     map[Ivec2][]ComboMatch
+	upkeepTimer      time.Duration
+	terrainStale     bool // A block started or stopped working since updateTerrain.
 }
 
 const (
-	MaxMobs   = 200
-	MaxDecals = 10
+	MaxMobs    = 200
+	MaxDecals  = 10
+	MaxDetours = 2 // Most placed attractions a visitor will go and see.
 )
 
-func NewLevel(state *State, sheet *twodee.Spritesheet, gameEventHandler *twodee.GameEventHandler) (level *Level, err error) {
+// Used for maps which do not have any spawn or exit markers.
+var (
+	DefaultSpawnPoints = []Ivec2{{3, 4}, {4, 9}, {5, 14}}
+	DefaultSinkPoint   = Ivec2{24, 9}
+)
+
+func NewLevel(def *LevelDef, state *State, sheet *twodee.Spritesheet, gameEventHandler *twodee.GameEventHandler) (level *Level, err error) {
 	var (
-		mobs    = make([]Mob, MaxMobs)
-		decals  = make([]*Decal, MaxDecals)
-		grid    *Grid
-		camera  *twodee.Camera
-		entries = []SpawnZone{
-			NewSpawnZone(Ivec2{3, 4}),
-			NewSpawnZone(Ivec2{4, 9}),
-			NewSpawnZone(Ivec2{5, 14}),
-		}
-		exit       = NewSpawnZone(Ivec2{24, 9})
+		mobs       = make([]Mob, MaxMobs)
+		decals     = make([]*Decal, MaxDecals)
+		ghosts     = make([]*Ghost, MaxGhosts)
+		grid       *Grid
+		combos     *ComboCatalog
+		exitKinds  *ExitCatalog
+		camera     *twodee.Camera
+		entries    []SpawnZone
+		exits      []Exit
+		spawns     = DefaultSpawnPoints
 		fearBuffer = NewCircularBuffer(100)
 	)
-	if grid, err = NewGrid(); err != nil {
+	if grid, err = NewGrid(def.Map); err != nil {
 		return
 	}
+	if combos, err = LoadComboCatalog(CombosFile); err != nil {
+		return
+	}
+	if exitKinds, err = LoadExitCatalog(ExitsFile); err != nil {
+		return
+	}
+	if markers := grid.Markers(MarkerSpawn); len(markers) > 0 {
+		spawns = markers
+	}
+	for _, kind := range exitKinds.Kinds {
+		for _, pt := range grid.Markers(kind.Marker) {
+			exits = append(exits, Exit{pt, kind})
+		}
+	}
+	if len(exits) == 0 {
+		exits = append(exits, Exit{DefaultSinkPoint, exitKinds.Find(MarkerSink)})
+	}
+	for _, pt := range spawns {
+		entries = append(entries, NewSpawnZone(pt))
+	}
 	for _, entry := range entries {
 		grid.AddSource(entry.Pos)
 	}
-	grid.SetSink(exit.Pos)
+	for _, exit := range exits {
+		grid.AddSink(exit.Pos)
+	}
+	for _, pt := range grid.Markers(MarkerAttraction) {
+		grid.AddTarget(pt)
+	}
 	grid.CalculateDistances()
 
 	for i := 0; i < MaxMobs; i++ {
@@ -113,8 +164,11 @@ func NewLevel(state *State, sheet *twodee.Spritesheet, gameEventHandler *twodee.
 	for i := 0; i < MaxDecals; i++ {
 		decals[i] = NewDecal()
 	}
+	for i := 0; i < MaxGhosts; i++ {
+		ghosts[i] = NewGhost()
+	}
 	if camera, err = twodee.NewCamera(
-		twodee.Rect(0, 0, float32(grid.Width()), float32(grid.Height())),
+		grid.FloorView(0),
 		twodee.Rect(0, 0, ScreenWidth, ScreenHeight),
 	); err != nil {
 		return
@@ -130,18 +184,38 @@ func NewLevel(state *State, sheet *twodee.Spritesheet, gameEventHandler *twodee.
 		State:            state,
 		Mobs:             mobs,
 		Decals:           decals,
+		Ghosts:           ghosts,
 		ActiveDecalCount: 0,
 		ActiveMobCount:   0,
 		entries:          entries,
-		exit:             exit,
+		exits:            exits,
 		blocks:           make(map[Ivec2]BlockPlacement),
+		rooms:            NewRooms(grid),
+		tour:             grid.Markers(MarkerAttraction),
+		sheet:            sheet,
+		combos:           combos,
+		activeCombos:     map[Ivec2][]ComboMatch{},
+		Avatar:           NewAvatar(mgl32.Vec2{float32(spawns[0].X()) + 2, float32(spawns[0].Y())}),
 		fearBuffer:       fearBuffer,
 		gameEventHandler: gameEventHandler,
-		durAtWinRating:   0,
+		Def:              def,
+		Objectives:       NewObjectives(def.Objectives),
+		stats:            NewStatsTracker(gameEventHandler),
 	}
 	return
 }
 
+func (l *Level) Delete() {
+	l.stats.Delete()
+}
+
+// Stats returns the totals gathered so far while playing this level.
+func (l *Level) Stats() Stats {
+	stats := l.stats.Stats
+	stats.PlayTime = l.playTime
+	return stats
+}
+
 func (l *Level) updateMobs(elapsed time.Duration) {
 	for i := range l.Mobs {
 		mob := &l.Mobs[i]
@@ -180,38 +254,56 @@ func (l *Level) updateSpawns(elapsed time.Duration) {
 }
 
 func (l *Level) updateBlocks(elapsed time.Duration) {
-	for pos, placement := range l.blocks {
-		posV := mgl32.Vec2{float32(pos.X()), float32(pos.Y())}.Add(mgl32.Vec2{0.5, 0.5}) // Adjust for center of block
-		fear := placement.Block.FearPerSec * elapsed.Seconds()
-		numHit := 0
+	for key, placement := range l.blocks {
+		if placement.Block.Door {
+			continue
+		}
+		if placement.DisabledFor > 0 {
+			placement.DisabledFor -= elapsed
+			l.blocks[key] = placement
+			if placement.Active() {
+				l.Grid.UpdateBlockState(placement, BlockNormal)
+				l.terrainStale = true
+			}
+		}
+		if !placement.Active() {
+			continue
+		}
+		posV := placement.Center()
+		fear := placement.Block.FearPerSec * elapsed.Seconds() * placement.Condition()
+		if fear > 0 {
+			fear *= l.comboMultiplier(key)
+		}
+		if room := l.rooms.At(placement.Pos); room != nil {
+			fear *= room.FearMultiplier()
+		}
+		hit := make([]int, 0, placement.Block.MaxTargets)
 		killed := make([]int, 0, placement.Block.MaxTargets)
 		for i := range l.Mobs {
 			mob := &l.Mobs[i]
-			if numHit >= placement.Block.MaxTargets || !mob.Enabled {
+			if len(hit) >= placement.Block.MaxTargets || !mob.Enabled {
 				break
 			}
-			if mob.Pos.Sub(posV).Len() <= placement.Block.Range {
-				numHit++
-				if alive := mob.IncreaseFear(fear); !alive {
+			if l.canScare(placement, mob) {
+				hit = append(hit, mob.Id)
+				if alive := mob.Scare(placement.Block, fear); !alive {
 					// Mob has been scared to death.
 					// TODO: uhhh this should be prettier.
 					killed = append(killed, i)
-					l.AddDecal(mob.Pos.Add(mgl32.Vec2{0, 0.5}), "ghost01_00", 2, 2*time.Second)
-					l.gameEventHandler.Enqueue(twodee.NewBasicGameEvent(PlayDeathEffect))
-					l.State.Rating = l.penalizeRating()
+					l.mobDied(mob)
 				}
 			}
 		}
-		if numHit > 0 {
-			l.Grid.UpdateBlockState(placement, BlockScaring)
-			switch placement.Block.Title {
-			case "Mr. Bones":
-				l.gameEventHandler.Enqueue(twodee.NewBasicGameEvent(PlayMrBonesEffect))
-			case "Spiketron 5000":
-				l.gameEventHandler.Enqueue(twodee.NewBasicGameEvent(PlaySpikesEffect))
-			case "Spiketron 6000 GT":
-				l.gameEventHandler.Enqueue(twodee.NewBasicGameEvent(PlaySpikesEffect))
+		if len(hit) > 0 {
+			placement = placement.Wear(elapsed)
+			l.blocks[key] = placement
+			if placement.Broken() {
+				l.Grid.UpdateBlockState(placement, BlockDisabled)
+				l.terrainStale = true
+			} else {
+				l.Grid.UpdateBlockState(placement, BlockScaring)
 			}
+			l.gameEventHandler.Enqueue(NewScareEvent(placement, posV, hit, fear))
 		} else {
 			l.Grid.UpdateBlockState(placement, BlockNormal)
 		}
@@ -224,39 +316,446 @@ func (l *Level) updateBlocks(elapsed time.Duration) {
 	}
 }
 
-// checkConditions checks to see if the player has lost. If so, it enqueues a
-// PlayerLost event.
-func (l *Level) checkConditions(elapsed time.Duration) {
-	if l.State.Rating <= FAIL_RATING {
-		l.gameEventHandler.Enqueue(twodee.NewBasicGameEvent(PlayerLost))
+// updateSabotage lets hostile visitors damage, disable or disarm the blocks
+// they walk past.
+func (l *Level) updateSabotage(elapsed time.Duration) {
+	for i := range l.Mobs {
+		mob := &l.Mobs[i]
+		if !mob.Enabled {
+			break
+		}
+		if !mob.Kind.Sabotages() {
+			continue
+		}
+		for key, placement := range l.blocks {
+			if placement.Block.Door || placement.Block.Health <= 0 || !placement.Active() {
+				continue
+			}
+			if mob.Pos.Sub(placement.Center()).Len() > mob.Kind.SabotageRange {
+				continue
+			}
+			placement.Health -= mob.Kind.DamagePerSec * elapsed.Seconds()
+			if mob.Kind.Disarms {
+				placement.Health = 0
+			}
+			if mob.Kind.DisableFor > 0 {
+				placement.DisabledFor = mob.Kind.DisableFor
+			}
+			l.blocks[key] = placement
+			if !placement.Active() {
+				l.Grid.UpdateBlockState(placement, BlockDisabled)
+				l.terrainStale = true
+				l.gameEventHandler.Enqueue(NewBlockEvent(BlockSabotaged, placement))
+			}
+		}
 	}
-	if l.State.Rating >= WIN_RATING {
-		l.durAtWinRating += elapsed
-		if l.durAtWinRating >= WIN_DURATION {
-			l.gameEventHandler.Enqueue(twodee.NewBasicGameEvent(PlayerWon))
+}
+
+// comboMultiplier returns the fear multiplier the placement at key gets from
+// combos with partners which are still working.
+func (l *Level) comboMultiplier(key Ivec2) float64 {
+	var multiplier = 1.0
+	for _, match := range l.activeCombos[key] {
+		if partner, ok := l.blocks[match.Partner]; ok && partner.Active() {
+			multiplier += match.Combo.FearBonus
 		}
+	}
+	return multiplier
+}
+
+// updateMaintenance lets handymen fix the most worn blocks, and charges
+// upkeep for blocks and wages for handymen every UpkeepEvery. Handymen who
+// can't be paid quit.
+func (l *Level) updateMaintenance(elapsed time.Duration) {
+	for i := 0; i < l.State.Handymen; i++ {
+		var (
+			worst Ivec2
+			found bool
+		)
+		for key, placement := range l.blocks {
+			if placement.Damaged() && (!found || placement.Condition() < l.blocks[worst].Condition()) {
+				worst = key
+				found = true
+			}
+		}
+		if !found {
+			break
+		}
+		l.restoreBlock(worst, l.blocks[worst].Repair(HandymanRepairPerSec*elapsed.Seconds()))
+	}
+	l.upkeepTimer += elapsed
+	if l.upkeepTimer < UpkeepEvery {
+		return
+	}
+	l.upkeepTimer -= UpkeepEvery
+	var upkeep int
+	for _, placement := range l.blocks {
+		upkeep += placement.Block.Upkeep
+	}
+	if wages := l.State.Handymen*HandymanWage + len(l.Minions)*MinionWage; upkeep+wages <= l.State.Geld {
+		upkeep += wages
 	} else {
-		l.durAtWinRating = 0
+		l.State.Handymen = 0
+		l.Minions = l.Minions[0:0]
+		l.State.Minions = 0
+	}
+	l.AddGeld(-minInt(upkeep, l.State.Geld))
+}
+
+// restoreBlock stores a repaired placement, waking it up if it was broken.
+func (l *Level) restoreBlock(key Ivec2, placement BlockPlacement) {
+	var wasActive = l.blocks[key].Active()
+	l.blocks[key] = placement
+	if !wasActive && placement.Active() {
+		l.Grid.UpdateBlockState(placement, BlockNormal)
+		l.terrainStale = true
+	}
+}
+
+// RepairBlock pays to fully repair the block under pos, returning false if
+// there is no damaged block there or it can't be afforded.
+func (l *Level) RepairBlock(pos mgl32.Vec2) bool {
+	var gridCoords = l.Grid.WorldToGrid(pos)
+	for key, placement := range l.blocks {
+		if !placement.Intersects(gridCoords) {
+			continue
+		}
+		cost := placement.RepairCost()
+		if !placement.Damaged() || cost > l.State.Geld {
+			return false
+		}
+		l.restoreBlock(key, placement.Repair(placement.Block.Health))
+		l.AddGeld(-cost)
+		return true
+	}
+	return false
+}
+
+// HireMinion takes on a minion to patrol the given route, returning false if
+// the route is no good or the minion can't be afforded.
+func (l *Level) HireMinion(route []Ivec2) bool {
+	if len(route) == 0 || len(l.Minions) >= MaxMinions || l.State.Geld < MinionCost {
+		return false
+	}
+	l.Minions = append(l.Minions, NewMinion(l.sheet, route, l.Grid))
+	l.State.Minions = len(l.Minions)
+	l.AddGeld(-MinionCost)
+	return true
+}
+
+// PatrolPoint returns the tile under pos if a minion could walk there.
+func (l *Level) PatrolPoint(pos mgl32.Vec2) (Ivec2, bool) {
+	pt := l.Grid.WorldToGrid(pos)
+	return pt, l.Grid.isWalkable(pt)
+}
+
+// SetPatrolHighlights marks the stops of a patrol route being drawn.
+func (l *Level) SetPatrolHighlights(route []Ivec2) {
+	l.clearHighlights()
+	for _, pt := range route {
+		l.Highlights = append(l.Highlights, Highlight{pt, "special_squares_02"})
+	}
+}
+
+// updateMinions walks minions along their patrols and lets them jump out at
+// visitors they come across.
+func (l *Level) updateMinions(elapsed time.Duration) {
+	var killed []int
+	for _, minion := range l.Minions {
+		minion.Update(elapsed, l.Grid)
+		floor := l.Grid.FloorAt(l.Grid.WorldToGrid(minion.Pos))
+		for i := range l.Mobs {
+			mob := &l.Mobs[i]
+			if !mob.Enabled {
+				break
+			}
+			if mob.Fear >= 10 || l.floorOf(mob) != floor || mob.Pos.Sub(minion.Pos).Len() > MinionRange {
+				continue // Already dead, or out of reach.
+			}
+			if minion.Ready() {
+				minion.Scare()
+			}
+			if !minion.Scaring() {
+				break
+			}
+			if alive := mob.IncreaseFear(MinionFearPerSec * elapsed.Seconds()); !alive {
+				killed = append(killed, i)
+				l.mobDied(mob)
+			}
+		}
+	}
+	sort.Ints(killed)
+	for i := len(killed) - 1; i > -1; i-- {
+		l.disableMob(killed[i])
+	}
+}
+
+// UseAbility has the avatar use ability, returning false if it isn't ready or
+// can't be used right now. Phase moves the avatar to the mouse, through any
+// walls in the way.
+func (l *Level) UseAbility(ability *Ability) bool {
+	var (
+		avatar = l.Avatar
+		floor  = l.Grid.FloorAt(l.Grid.WorldToGrid(avatar.Pos))
+		killed []int
+	)
+	if !avatar.Ready(ability) {
+		return false
+	}
+	switch ability {
+	case &BooAbility:
+		for i := range l.Mobs {
+			mob := &l.Mobs[i]
+			if !mob.Enabled {
+				break
+			}
+			if l.floorOf(mob) != floor || mob.Pos.Sub(avatar.Pos).Len() > ability.Range {
+				continue
+			}
+			if alive := mob.IncreaseFear(ability.Fear); !alive {
+				killed = append(killed, i)
+				l.mobDied(mob)
+			}
+		}
+	case &ChillAbility:
+		avatar.chillFor = ability.Duration
+	case &PhaseAbility:
+		var (
+			target = l.GetMouse()
+			pt     = l.Grid.WorldToGrid(target)
+		)
+		if target.Sub(avatar.Pos).Len() > ability.Range || !l.Grid.isWalkable(pt) || l.Grid.FloorAt(pt) != floor {
+			return false
+		}
+		avatar.Pos = target
+		avatar.moving = false
+	}
+	avatar.use(ability)
+	for i := len(killed) - 1; i > -1; i-- {
+		l.disableMob(killed[i])
+	}
+	return true
+}
+
+// mobDied raises the ghost of a mob which was scared to death and takes the
+// hit to the rating.
+func (l *Level) mobDied(mob *Mob) {
+	l.AddGhost(mob.Pos)
+	l.gameEventHandler.Enqueue(NewMobEvent(MobDied, mob))
+	l.setRating(l.penalizeRating(DeathPenalty))
+}
+
+// HireHandyman takes on another handyman, returning false if the hiring fee
+// can't be afforded.
+func (l *Level) HireHandyman() bool {
+	if l.State.Geld < HandymanCost {
+		return false
+	}
+	l.State.Handymen++
+	l.AddGeld(-HandymanCost)
+	return true
+}
+
+// DisabledHighlights marks the tiles of every block which is broken or
+// disabled.
+func (l *Level) DisabledHighlights() (highlights []Highlight) {
+	for _, placement := range l.blocks {
+		if !placement.Active() {
+			highlights = append(highlights, l.placementHighlights(placement, "special_squares_03")...)
+		}
+	}
+	return
+}
+
+// placementHighlights returns highlights with frame over every tile of the
+// placement.
+func (l *Level) placementHighlights(placement BlockPlacement, frame string) (highlights []Highlight) {
+	base := placement.Pos.Plus(placement.Block.Offset)
+	for y, row := range placement.Block.Variants[placement.Variant] {
+		for x, tmpl := range row {
+			if tmpl != nil {
+				highlights = append(highlights, Highlight{base.Plus(Ivec2{int32(x), int32(y)}), frame})
+			}
+		}
+	}
+	return
+}
+
+// updateScreams makes mobs which have just become terrified scream, which
+// frightens the mobs around them and may set them off in turn. Chains of
+// screams are cut off after MaxScreamChain.
+func (l *Level) updateScreams() {
+	var screamers []int
+	for i := range l.Mobs {
+		mob := &l.Mobs[i]
+		if !mob.Enabled {
+			break
+		}
+		if mob.screamed && mob.Fear < ScreamRearm {
+			mob.screamed = false
+			mob.screamChain = 0
+		}
+		if !mob.screamed && mob.Fear > ScreamThreshold {
+			mob.screamed = true
+			screamers = append(screamers, i)
+		}
+	}
+	for len(screamers) > 0 {
+		screamer := &l.Mobs[screamers[0]]
+		screamers = screamers[1:]
+		l.AddDecal(screamer.Pos.Add(mgl32.Vec2{0, 1.5}), "bubble_01", 1, 500*time.Millisecond)
+		l.gameEventHandler.Enqueue(NewMobEvent(MobScreamed, screamer))
+		if screamer.screamChain >= MaxScreamChain {
+			continue
+		}
+		for i := range l.Mobs {
+			mob := &l.Mobs[i]
+			if !mob.Enabled {
+				break
+			}
+			if mob == screamer || mob.Pos.Sub(screamer.Pos).Len() > ScreamRange || l.floorOf(mob) != l.floorOf(screamer) {
+				continue
+			}
+			if fear := math.Min(ScreamFear, ScreamMaxFear-mob.Fear); fear > 0 {
+				mob.IncreaseFear(fear)
+			}
+			if !mob.screamed && mob.Fear > ScreamThreshold {
+				mob.screamed = true
+				mob.screamChain = screamer.screamChain + 1
+				screamers = append(screamers, i)
+			}
+		}
+	}
+}
+
+func (l *Level) floorOf(mob *Mob) int {
+	return l.Grid.FloorAt(l.Grid.WorldToGrid(mob.Pos))
+}
+
+// updateRooms counts the mobs in each room.
+func (l *Level) updateRooms() {
+	l.rooms.ClearOccupancy()
+	for i := range l.Mobs {
+		if !l.Mobs[i].Enabled {
+			break
+		}
+		if room := l.rooms.At(l.Grid.WorldToGrid(l.Mobs[i].Pos)); room != nil {
+			room.Occupancy++
+		}
+	}
+}
+
+// SetFloor moves the view to the given floor, returning false if there is no
+// such floor.
+func (l *Level) SetFloor(floor int) bool {
+	if floor < 0 || floor >= len(l.Grid.Floors()) {
+		return false
+	}
+	l.Floor = floor
+	l.Camera.SetWorldBounds(l.Grid.FloorView(floor))
+	return true
+}
+
+// RoomAt returns the room under the given world coordinates, if any.
+func (l *Level) RoomAt(pos mgl32.Vec2) *Room {
+	return l.rooms.At(l.Grid.WorldToGrid(pos))
+}
+
+// canScare returns true if mob is within range of the placement and not
+// hidden from it by anything opaque.
+func (l *Level) canScare(placement BlockPlacement, mob *Mob) bool {
+	if mob.Pos.Sub(placement.Center()).Len() > placement.Block.Range {
+		return false
+	}
+	return l.Grid.CanSee(placement, l.Grid.WorldToGrid(mob.Pos))
+}
+
+// checkConditions updates the level's objectives, sending an event for each
+// one which is completed or failed. Once every goal is complete or any limit
+// is broken it sends PlayerWon or PlayerLost, just once.
+func (l *Level) checkConditions(elapsed time.Duration) {
+	if l.over {
+		return
+	}
+	var (
+		stats = l.Stats()
+		goals = 0
+		won   = true
+		lost  = false
+	)
+	for _, objective := range l.Objectives {
+		if objective.Update(elapsed, l.State, stats) {
+			switch objective.Status {
+			case ObjectiveMet:
+				l.gameEventHandler.Enqueue(NewObjectiveEvent(ObjectiveCompleted, objective))
+			case ObjectiveMissed:
+				l.gameEventHandler.Enqueue(NewObjectiveEvent(ObjectiveFailed, objective))
+			}
+		}
+		if objective.IsGoal() {
+			goals++
+			won = won && objective.Status == ObjectiveMet
+		}
+		if objective.Status == ObjectiveMissed {
+			lost = true
+		}
+	}
+	switch {
+	case lost:
+		l.over = true
+		l.gameEventHandler.Enqueue(NewGameOverEvent(PlayerLost, l.State, stats))
+	case won && goals > 0:
+		l.over = true
+		l.gameEventHandler.Enqueue(NewGameOverEvent(PlayerWon, l.State, stats))
 	}
 }
 
 // Update computes a new simulation step for this level.
 func (l *Level) Update(elapsed time.Duration) {
 	l.updateBlocks(elapsed)
+	l.updateSabotage(elapsed)
+	l.updateMaintenance(elapsed)
+	if l.terrainStale {
+		l.updateTerrain()
+	}
+	l.updateMinions(elapsed)
+	l.Avatar.Update(elapsed, l.Grid)
+	l.updateScreams()
 	l.updateMobs(elapsed)
+	l.updateRooms()
 	l.updateSpawns(elapsed)
 	l.updateDecals(elapsed)
+	l.updateGhosts(elapsed)
 	l.Grid.Update(elapsed)
+	l.playTime += elapsed
 	l.checkConditions(elapsed)
 }
 
+// Win ends the level as won with the score so far, as if every goal had been
+// met.
+func (l *Level) Win() {
+	if l.over {
+		return
+	}
+	l.over = true
+	l.gameEventHandler.Enqueue(NewGameOverEvent(PlayerWon, l.State, l.Stats()))
+}
+
+// SetMouse stores the mouse position relative to the floor in view, which is
+// what the HUD draws the cursor with.
 func (l *Level) SetMouse(screenX, screenY float32) {
 	x, y := l.Camera.ScreenToWorldCoords(screenX, screenY)
-	l.State.MousePos = mgl32.Vec2{x, y}
+	l.State.MousePos = mgl32.Vec2{x, y}.Sub(l.floorOffset())
 }
 
+// GetMouse returns the mouse position in world coordinates.
 func (l *Level) GetMouse() mgl32.Vec2 {
-	return l.State.MousePos
+	return l.State.MousePos.Add(l.floorOffset())
+}
+
+func (l *Level) floorOffset() mgl32.Vec2 {
+	offset := l.Grid.Floors()[l.Floor].Offset
+	return mgl32.Vec2{float32(offset.X()), float32(offset.Y())}
 }
 
 func (l *Level) SetCursor(frame string) {
@@ -267,15 +766,76 @@ func (l *Level) GetCursor() string {
 	return l.State.MouseCursor
 }
 
-func (l *Level) SetBlock(pos mgl32.Vec2, block *Block, variant int) {
+// SetBlock buys and places the block at pos, returning whether the player
+// could afford it and there was room.
+func (l *Level) SetBlock(pos mgl32.Vec2, block *Block, variant int) bool {
 	var (
 		gridCoords = l.Grid.WorldToGrid(pos)
-		placement  = BlockPlacement{gridCoords, block, variant}
+		placement  = BlockPlacement{
+			Pos:     gridCoords,
+			Block:   block,
+			Variant: variant,
+			Health:  block.Health,
+		}
 	)
+	if block.Cost > l.State.Geld {
+		return false
+	}
 	if center, ok := l.Grid.SetBlock(placement); ok {
 		l.blocks[center] = placement
-		l.Grid.CalculateDistances()
+		if block.Door {
+			l.Grid.SetDoorOpen(placement, true)
+		}
+		if block.Attraction > 0 {
+			l.Grid.AddTarget(center)
+		}
+		l.updateTerrain()
+		l.gameEventHandler.Enqueue(NewBlockEvent(BlockPlaced, placement))
+		l.AddGeld(-block.Cost)
+		return true
 	}
+	return false
+}
+
+// ToggleDoor opens or closes the door under pos, returning whether there was
+// a door which could be toggled. Doors refuse to close on top of a mob, or if
+// closing them would leave any visitor without a way out.
+func (l *Level) ToggleDoor(pos mgl32.Vec2) bool {
+	var (
+		gridCoords = l.Grid.WorldToGrid(pos)
+		mobs       []Ivec2
+	)
+	for _, placement := range l.blocks {
+		if !placement.Block.Door || !placement.Intersects(gridCoords) {
+			continue
+		}
+		if l.Grid.IsDoorOpen(placement) {
+			for i := range l.Mobs {
+				if !l.Mobs[i].Enabled {
+					break
+				}
+				pt := l.Grid.WorldToGrid(l.Mobs[i].Pos)
+				if placement.Intersects(pt) {
+					return false
+				}
+				mobs = append(mobs, pt)
+			}
+			l.Grid.SetDoorOpen(placement, false)
+			l.updateTerrain()
+			if !l.Grid.HasRoute(mobs) {
+				l.Grid.SetDoorOpen(placement, true)
+				l.updateTerrain()
+				return false
+			}
+			l.gameEventHandler.Enqueue(NewBlockEvent(DoorClosed, placement))
+		} else {
+			l.Grid.SetDoorOpen(placement, true)
+			l.updateTerrain()
+			l.gameEventHandler.Enqueue(NewBlockEvent(DoorOpened, placement))
+		}
+		return true
+	}
+	return false
 }
 
 func (l *Level) DeleteBlock() {
@@ -284,8 +844,51 @@ func (l *Level) DeleteBlock() {
 	}
 	if center, ok := l.Grid.DeleteBlock(*l.deleteable); ok {
 		delete(l.blocks, center)
+		l.Grid.RemoveTarget(center)
+		l.gameEventHandler.Enqueue(NewBlockEvent(BlockRemoved, *l.deleteable))
 		l.UnsetHighlights()
-		l.Grid.CalculateDistances()
+		l.updateTerrain()
+	}
+}
+
+// updateTerrain reapplies the speed modifiers of all working blocks,
+// recalculates the routes taken by mobs and reassigns blocks to rooms.
+func (l *Level) updateTerrain() {
+	l.terrainStale = false
+	l.rooms.SetBlocks(l.blocks)
+	l.Grid.ClearSpeedModifiers()
+	l.Grid.ClearPathCosts()
+	for key := range l.activeCombos {
+		delete(l.activeCombos, key)
+	}
+	for key, placement := range l.blocks {
+		if !placement.Active() {
+			continue
+		}
+		l.activeCombos[key] = l.combos.Find(placement, l.blocks)
+		for _, match := range l.activeCombos[key] {
+			if match.Combo.Speed == 0 {
+				continue
+			}
+			for _, pt := range l.visibleArea(placement) {
+				l.Grid.AddSpeedModifier(pt, match.Combo.Speed)
+			}
+		}
+		if placement.Block.Speed == 0 && placement.Block.PathCost == 0 {
+			continue
+		}
+		for _, pt := range l.visibleArea(placement) {
+			if placement.Block.Speed != 0 {
+				l.Grid.AddSpeedModifier(pt, placement.Block.Speed)
+			}
+			if placement.Block.PathCost != 0 {
+				l.Grid.AddPathCost(pt, placement.Block.PathCost)
+			}
+		}
+	}
+	l.Grid.CalculateDistances()
+	for _, minion := range l.Minions {
+		minion.Repath()
 	}
 }
 
@@ -295,13 +898,23 @@ func (l *Level) calculateRating() int {
 	return int(math.Floor(l.fearBuffer.Sample() + 0.5))
 }
 
-func (l *Level) penalizeRating() int {
-	l.fearBuffer.AdjustAll(-1.0, 0.0)
+func (l *Level) setRating(rating int) {
+	if rating != l.State.Rating {
+		delta := rating - l.State.Rating
+		l.State.Rating = rating
+		l.gameEventHandler.Enqueue(NewAmountEvent(RatingChanged, rating, delta))
+	}
+}
+
+// penalizeRating lowers the fear of every visitor the rating remembers.
+func (l *Level) penalizeRating(penalty float64) int {
+	l.fearBuffer.AdjustAll(-penalty, 0.0)
 	return l.calculateRating()
 }
 
 func (l *Level) clearHighlights() {
 	l.Highlights = l.Highlights[0:0]
+	l.ComboPreview = l.ComboPreview[0:0]
 }
 
 func (l *Level) SetHighlights(pos mgl32.Vec2, block *Block, variant int) {
@@ -333,6 +946,13 @@ func (l *Level) RefreshHighlights() {
 	if !l.Grid.IsBlockValid(*l.highlighted) || l.State.Geld < l.highlighted.Block.Cost {
 		frame = "special_squares_03"
 	}
+	for _, pt := range l.visibleArea(*l.highlighted) {
+		l.Highlights = append(l.Highlights, Highlight{pt, "special_squares_01"})
+	}
+	for _, match := range l.combos.Find(*l.highlighted, l.blocks) {
+		l.ComboPreview = append(l.ComboPreview, match.Combo.Name)
+		l.Highlights = append(l.Highlights, l.placementHighlights(l.blocks[match.Partner], "special_squares_00")...)
+	}
 	for y := 0; y < len(l.highlighted.Block.Variants[l.highlighted.Variant]); y++ {
 		for x := 0; x < len(l.highlighted.Block.Variants[l.highlighted.Variant][y]); x++ {
 			if l.highlighted.Block.Variants[l.highlighted.Variant][y][x] == nil {
@@ -346,6 +966,33 @@ func (l *Level) RefreshHighlights() {
 	}
 }
 
+// visibleArea returns the points within range of the placement which it has
+// line of sight to, excluding the placement's own tiles.
+func (l *Level) visibleArea(placement BlockPlacement) (points []Ivec2) {
+	var (
+		center = placement.Center()
+		r      = int32(math.Ceil(float64(placement.Block.Range)))
+		pt     Ivec2
+		offset mgl32.Vec2
+	)
+	for y := placement.Pos.Y() - r; y <= placement.Pos.Y()+r; y++ {
+		for x := placement.Pos.X() - r; x <= placement.Pos.X()+r; x++ {
+			pt = Ivec2{x, y}
+			offset = mgl32.Vec2{float32(x) + 0.5, float32(y) + 0.5}.Sub(center)
+			if placement.Intersects(pt) || l.Grid.IsOpaque(pt) {
+				continue
+			}
+			if offset.Len() > placement.Block.Range {
+				continue
+			}
+			if l.Grid.CanSee(placement, pt) {
+				points = append(points, pt)
+			}
+		}
+	}
+	return
+}
+
 func (l *Level) SetDeleteHighlights(pos mgl32.Vec2) {
 	var (
 		gridCoords = l.Grid.WorldToGrid(pos)
@@ -387,26 +1034,95 @@ func (l *Level) AddMob(pos mgl32.Vec2) {
 		// TODO: Do we need an error state?
 		return
 	}
-	l.Mobs[l.ActiveMobCount].Activate(pos, 2.0)
+	l.nextMobId++
+	l.Mobs[l.ActiveMobCount].Activate(l.nextMobId, PickMobKind(), pos, 2.0, l.planItinerary())
+	l.gameEventHandler.Enqueue(NewMobEvent(MobSpawned, &l.Mobs[l.ActiveMobCount]))
 	l.ActiveMobCount++
 }
 
+// planItinerary returns the attractions a new visitor wants to see: every
+// stop of the map's tour in order, followed by a few placed attractions they
+// picked according to how attractive they are.
+func (l *Level) planItinerary() []Ivec2 {
+	var (
+		itinerary = append([]Ivec2{}, l.tour...)
+		picks     []BlockPlacement
+	)
+	for _, placement := range l.blocks {
+		if rand.Float64() < placement.Block.Attraction {
+			picks = append(picks, placement)
+		}
+	}
+	sort.Sort(ByAttraction(picks))
+	for i := 0; i < len(picks) && i < MaxDetours; i++ {
+		itinerary = append(itinerary, picks[i].Pos)
+	}
+	return itinerary
+}
+
+// VisitAttraction is called when a mob reaches an attraction on its
+// itinerary.
+func (l *Level) VisitAttraction(mob *Mob) {
+	l.gameEventHandler.Enqueue(NewMobEvent(AttractionVisited, mob))
+}
+
 func (l *Level) AddGeld(amount int) {
+	if amount == 0 {
+		return
+	}
 	l.State.Geld += amount
+	l.gameEventHandler.Enqueue(NewAmountEvent(GeldChanged, l.State.Geld, amount))
 	l.RefreshHighlights()
 }
 
+// ChooseExit returns the exit the mob should head for: the cheapest to reach,
+// with a detour allowed for exits the mob prefers at its current fear.
+func (l *Level) ChooseExit(mob *Mob) (best Exit, ok bool) {
+	var (
+		pt       = l.Grid.WorldToGrid(mob.Pos)
+		bestCost int32
+	)
+	for _, exit := range l.exits {
+		cost := l.Grid.RouteCost(pt, l.Grid.SinkField(exit.Pos))
+		if cost < 0 {
+			continue
+		}
+		if !exit.Prefers(mob.Fear) {
+			cost += ExitDetourCost
+		}
+		if !ok || cost < bestCost {
+			best, bestCost, ok = exit, cost, true
+		}
+	}
+	return
+}
+
+func (l *Level) exitAt(pt Ivec2) Exit {
+	for _, exit := range l.exits {
+		if exit.Pos == pt {
+			return exit
+		}
+	}
+	return l.exits[0]
+}
+
 func (l *Level) despawnMob(i int) {
-	var fear = l.Mobs[i].Fear
+	var (
+		fear = l.Mobs[i].Review()
+		exit = l.exitAt(l.Mobs[i].Exit)
+	)
 	switch {
 	case fear < 5:
 		l.AddDecal(l.Mobs[i].Pos.Add(mgl32.Vec2{0, 1.5}), "bubble_00", 1, 500*time.Millisecond)
 	case fear > 8:
 		l.AddDecal(l.Mobs[i].Pos.Add(mgl32.Vec2{0, 1.5}), "bubble_01", 1, 500*time.Millisecond)
 	}
-	l.fearBuffer.AddEntry(fear)
-	l.State.Rating = l.calculateRating()
-	l.AddGeld(int(math.Floor(fear + 0.5)))
+	evt := NewMobEvent(MobExited, &l.Mobs[i])
+	evt.Review = fear
+	l.gameEventHandler.Enqueue(evt)
+	l.fearBuffer.AddEntry(fear + exit.Kind.RatingBonus)
+	l.setRating(l.calculateRating())
+	l.AddGeld(exit.Geld(fear))
 	l.disableMob(i)
 }
 
@@ -416,6 +1132,47 @@ func (l *Level) disableMob(i int) {
 	l.Mobs[l.ActiveMobCount].Disable()
 }
 
+// AddGhost raises the ghost of a mob which died at pos. If there are already
+// too many ghosts about, the ghost just floats away.
+func (l *Level) AddGhost(pos mgl32.Vec2) {
+	floor := l.Grid.FloorAt(l.Grid.WorldToGrid(pos))
+	for _, ghost := range l.Ghosts {
+		if !ghost.Enabled && floor >= 0 {
+			ghost.Activate(pos, l.Grid.Floors()[floor].Bounds())
+			return
+		}
+	}
+	l.AddDecal(pos.Add(mgl32.Vec2{0, 0.5}), "ghost01_00", 2, 2*time.Second)
+}
+
+// updateGhosts moves ghosts around, lets them haunt mobs on their floor and
+// charges for ghosts which outstay their welcome.
+func (l *Level) updateGhosts(elapsed time.Duration) {
+	for _, ghost := range l.Ghosts {
+		if !ghost.Enabled {
+			continue
+		}
+		if ghost.Update(elapsed) {
+			l.setRating(l.penalizeRating(GhostPenalty))
+		}
+		if ghost.Expired() {
+			ghost.Disable()
+			l.AddDecal(ghost.Pos.Add(mgl32.Vec2{0, 0.5}), "ghost01_00", 2, 2*time.Second)
+			continue
+		}
+		floor := l.Grid.FloorAt(l.Grid.WorldToGrid(ghost.Pos))
+		for i := range l.Mobs {
+			mob := &l.Mobs[i]
+			if !mob.Enabled {
+				break
+			}
+			if l.floorOf(mob) == floor {
+				ghost.Haunt(elapsed, mob)
+			}
+		}
+	}
+}
+
 func (l *Level) AddDecal(pos mgl32.Vec2, frame string, move float32, duration time.Duration) {
 	if l.ActiveDecalCount >= MaxDecals {
 		return
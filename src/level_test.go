@@ -0,0 +1,196 @@
+// Copyright 2015 Pikkpoiss
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"../lib/twodee"
+	"github.com/go-gl/mathgl/mgl32"
+	"testing"
+)
+
+// newTestLevel returns a level on grid with no spawns, exits or blocks.
+func newTestLevel(g *Grid) *Level {
+	var (
+		handler = twodee.NewGameEventHandler(NumGameEventTypes)
+		mobs    = make([]Mob, MaxMobs)
+		ghosts  = make([]*Ghost, MaxGhosts)
+		decals  = make([]*Decal, MaxDecals)
+	)
+	for i := range mobs {
+		mobs[i] = *newTestMob()
+	}
+	for i := range ghosts {
+		ghosts[i] = NewGhost()
+	}
+	for i := range decals {
+		decals[i] = NewDecal()
+	}
+	return &Level{
+		Grid:             g,
+		State:            NewState(),
+		Mobs:             mobs,
+		Ghosts:           ghosts,
+		Decals:           decals,
+		Avatar:           NewAvatar(mgl32.Vec2{-10, -10}),
+		blocks:           map[Ivec2]BlockPlacement{},
+		rooms:            NewRooms(g),
+		combos:           &ComboCatalog{},
+		activeCombos:     map[Ivec2][]ComboMatch{},
+		fearBuffer:       NewCircularBuffer(100),
+		gameEventHandler: handler,
+		Def:              &LevelDef{},
+		stats:            NewStatsTracker(handler),
+	}
+}
+
+func TestVisibleAreaIsSymmetric(t *testing.T) {
+	var (
+		level     = newTestLevel(newTestGrid(9, 9))
+		placement = BlockPlacement{Pos: Ivec2{4, 4}, Block: &SkellyBlock}
+		visible   = map[Ivec2]bool{}
+	)
+	for _, pt := range level.visibleArea(placement) {
+		visible[pt] = true
+	}
+	if len(visible) != 8 {
+		t.Errorf("Expected the 8 tiles around the block to be visible, got %v", len(visible))
+	}
+	for pt := range visible {
+		mirrored := Ivec2{8 - pt.X(), 8 - pt.Y()}
+		if !visible[mirrored] {
+			t.Errorf("Expected %v to be visible like %v", mirrored, pt)
+		}
+	}
+	for _, pt := range []Ivec2{{3, 3}, {5, 5}, {3, 5}, {5, 3}} {
+		if !visible[pt] {
+			t.Errorf("Expected diagonal %v to be visible", pt)
+		}
+	}
+}
+
+func TestChooseExit(t *testing.T) {
+	var (
+		level   = newTestLevel(newTestGrid(14, 3))
+		catalog *ExitCatalog
+		err     error
+	)
+	if catalog, err = LoadExitCatalog(ExitsFile); err != nil {
+		t.Fatalf("Could not load exits: %v", err)
+	}
+	level.exits = []Exit{
+		{Ivec2{0, 1}, catalog.Find("emergency_exit")},
+		{Ivec2{6, 0}, catalog.Find("gift_shop")},
+		{Ivec2{12, 1}, catalog.Find(MarkerSink)},
+	}
+	for _, exit := range level.exits {
+		if exit.Kind == nil {
+			t.Fatalf("Expected every kind of exit in %v", ExitsFile)
+		}
+		level.Grid.AddSink(exit.Pos)
+	}
+	level.Grid.CalculateDistances()
+	for _, ct := range []struct {
+		pos      mgl32.Vec2
+		fear     float64
+		expected Ivec2
+	}{
+		{mgl32.Vec2{2.5, 1.5}, 2, Ivec2{6, 0}},   // Calm visitors walk past the emergency exit to the shop.
+		{mgl32.Vec2{2.5, 1.5}, 9, Ivec2{0, 1}},   // Terrified visitors bolt.
+		{mgl32.Vec2{2.5, 1.5}, 6, Ivec2{12, 1}},  // Nobody else is worth the detour.
+		{mgl32.Vec2{11.5, 1.5}, 2, Ivec2{12, 1}}, // The shop isn't worth walking back for.
+	} {
+		mob := newTestMob()
+		mob.Pos = ct.pos
+		mob.Fear = ct.fear
+		if exit, ok := level.ChooseExit(mob); !ok || exit.Pos != ct.expected {
+			t.Errorf("Expected mob at %v with fear %v to leave by %v, got %v", ct.pos, ct.fear, ct.expected, exit.Pos)
+		}
+	}
+}
+
+// addTestMobs enables a mob with the given fear at each position.
+func addTestMobs(level *Level, fear float64, positions ...mgl32.Vec2) {
+	for i, pos := range positions {
+		mob := &level.Mobs[level.ActiveMobCount+i]
+		mob.Enabled = true
+		mob.Pos = pos
+		mob.Fear = fear
+	}
+	level.ActiveMobCount += len(positions)
+}
+
+func TestScreamChainsStop(t *testing.T) {
+	var level = newTestLevel(newTestGrid(20, 3))
+	// Each mob can only hear its neighbours.
+	for x := float32(1); x < 20; x += 2.5 {
+		addTestMobs(level, ScreamThreshold-0.5, mgl32.Vec2{x, 1.5})
+	}
+	level.Mobs[0].Fear = ScreamThreshold + 0.5
+	level.updateScreams()
+	for i := 0; i < level.ActiveMobCount; i++ {
+		mob := &level.Mobs[i]
+		if screamed := i <= MaxScreamChain; mob.screamed != screamed {
+			t.Errorf("Expected mob %v screamed to be %v, fear %v", i, screamed, mob.Fear)
+		}
+		if i <= MaxScreamChain && mob.screamChain != i {
+			t.Errorf("Expected mob %v to be link %v in the chain, got %v", i, i, mob.screamChain)
+		}
+	}
+	if fear := level.Mobs[MaxScreamChain+1].Fear; fear != ScreamThreshold-0.5 {
+		t.Errorf("Expected mobs past the end of the chain to be left alone, got %v", fear)
+	}
+}
+
+func TestScreamsNeverKill(t *testing.T) {
+	var level = newTestLevel(newTestGrid(5, 5))
+	for i := 0; i < 20; i++ {
+		addTestMobs(level, ScreamMaxFear-0.1, mgl32.Vec2{2.5, 2.5})
+	}
+	level.updateScreams()
+	for i := 0; i < level.ActiveMobCount; i++ {
+		if mob := &level.Mobs[i]; mob.Fear > ScreamMaxFear || !mob.screamed {
+			t.Errorf("Expected mob %v to scream without passing %v, got %v", i, ScreamMaxFear, mob.Fear)
+		}
+	}
+}
+
+func TestScreamsRearm(t *testing.T) {
+	var (
+		level = newTestLevel(newTestGrid(5, 5))
+		mob   = &level.Mobs[0]
+	)
+	addTestMobs(level, ScreamThreshold+1, mgl32.Vec2{2.5, 2.5})
+	level.updateScreams()
+	mob.screamChain = 2
+	level.updateScreams()
+	if !mob.screamed || mob.screamChain != 2 {
+		t.Fatalf("Expected mob to scream once")
+	}
+	mob.Fear = ScreamRearm + 0.5
+	level.updateScreams()
+	if !mob.screamed {
+		t.Errorf("Expected mob to stay screamed until calmer than %v", ScreamRearm)
+	}
+	mob.Fear = ScreamRearm - 0.5
+	level.updateScreams()
+	if mob.screamed || mob.screamChain != 0 {
+		t.Errorf("Expected mob to rearm once calm")
+	}
+	mob.Fear = ScreamThreshold + 1
+	level.updateScreams()
+	if !mob.screamed {
+		t.Errorf("Expected mob to scream again")
+	}
+}
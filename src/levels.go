@@ -0,0 +1,72 @@
+// Copyright 2015 Pikkpoiss
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+)
+
+const LevelsFile = "resources/levels.json"
+
+// LevelDef describes one level of the game.
+type LevelDef struct {
+	Name       string
+	Map        string
+	Objectives []ObjectiveDef // DefaultObjectives if empty.
+	Par        Par
+}
+
+type LevelCatalog struct {
+	Levels []*LevelDef
+}
+
+func LoadLevelCatalog(path string) (catalog *LevelCatalog, err error) {
+	var data []byte
+	if data, err = ioutil.ReadFile(path); err != nil {
+		return
+	}
+	catalog = &LevelCatalog{}
+	if err = json.Unmarshal(data, catalog); err != nil {
+		return
+	}
+	if len(catalog.Levels) == 0 {
+		err = fmt.Errorf("No levels in %v", path)
+		return
+	}
+	for _, def := range catalog.Levels {
+		if def.Map == "" {
+			err = fmt.Errorf("No map for level %v in %v", def.Name, path)
+			return
+		}
+		if len(def.Objectives) == 0 {
+			def.Objectives = DefaultObjectives
+		}
+		for _, objective := range def.Objectives {
+			if err = objective.Validate(); err != nil {
+				err = fmt.Errorf("%v for level %v in %v", err, def.Name, path)
+				return
+			}
+		}
+	}
+	return
+}
+
+// Level returns the definition of the i-th level, wrapping around after the
+// last one.
+func (c *LevelCatalog) Level(i int) *LevelDef {
+	return c.Levels[i%len(c.Levels)]
+}
@@ -19,38 +19,288 @@ import (
 	"fmt"
 	"github.com/pikkpoiss/tmxgo"
 	"io/ioutil"
+	"math"
+	"strconv"
+	"strings"
 )
 
-func LoadTiledMap(path string) (grid *twodee.Grid, err error) {
-	var (
-		data  []byte
-		m     *tmxgo.Map
-		tiles []*tmxgo.Tile
-		tile  *tmxgo.Tile
-		x     int32
-		y     int32
-	)
+// Object types which may be used in a map's object layers.
+const (
+	MarkerSpawn      = "spawn"
+	MarkerSink       = "sink"       // The main exit. Other exits are listed in ExitsFile.
+	MarkerAttraction = "attraction" // Visited in order by every visitor.
+	MarkerStairs     = "stairs"     // Linked to stairs of the same name.
+)
+
+// Tile layers loaded in addition to "ground", in drawing order. Tiles on the
+// walls layer block movement and sight unless their properties say otherwise.
+var MapLayerNames = []string{"walls", "decoration", "overlay"}
+
+// TileProperties holds the custom properties a tileset or tile may set in
+// Tiled. Properties set on a tile override those set on its tileset.
+type TileProperties struct {
+	Passable bool
+	Opaque   bool
+	Speed    float64 // Multiplier applied to mobs crossing the tile.
+	Recovery float64 // Fear per second lost by mobs on the tile.
+	Region   string
+	Theme    string // Theme of the room the tile belongs to.
+	Frame    string // Overrides the spritesheet frame used to draw the tile.
+}
+
+var (
+	GroundProperties = TileProperties{Passable: true, Speed: 1.0}
+	WallProperties   = TileProperties{Opaque: true, Speed: 1.0}
+)
+
+// MapLayer holds the frames of a purely decorative tile layer.
+type MapLayer struct {
+	Name   string
+	Above  bool // Drawn on top of mobs and blocks.
+	width  int32
+	frames []string
+}
+
+// Frame returns the frame drawn at pt, or an empty string if there is none.
+func (l *MapLayer) Frame(pt Ivec2) string {
+	return l.frames[pt.Y()*l.width+pt.X()]
+}
+
+type MapMarker struct {
+	Kind  string
+	Name  string
+	Pos   Ivec2
+	Floor int
+}
+
+// TiledFloor is one floor of a map. Maps may hold several floors by naming
+// each floor's layers and object layers "<floor>/<layer>".
+type TiledFloor struct {
+	Name       string
+	Background *twodee.Grid
+	Layers     []*MapLayer
+	Markers    []MapMarker
+}
+
+type TiledMap struct {
+	Floors []*TiledFloor
+}
+
+// floorLayer splits a layer name into the floor it belongs to and its name on
+// that floor. Layers without a floor belong to the unnamed floor.
+func floorLayer(name string) (floor, layer string) {
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		return name[:i], name[i+1:]
+	}
+	return "", name
+}
+
+// tileProperties applies the properties of tile and its tileset on top of
+// props.
+func tileProperties(tile *tmxgo.Tile, props TileProperties) (TileProperties, error) {
+	var err error
+	if props.Frame == "" {
+		props.Frame = fmt.Sprintf("tiles_%02v", tile.Index)
+	}
+	if tile.Tileset == nil {
+		return props, nil
+	}
+	if err = props.apply(tile.Tileset.Properties); err != nil {
+		return props, err
+	}
+	for _, info := range tile.Tileset.Tiles {
+		if info.Id == tile.Index {
+			err = props.apply(info.Properties)
+			break
+		}
+	}
+	return props, err
+}
+
+func (p *TileProperties) apply(properties []tmxgo.Property) (err error) {
+	for _, prop := range properties {
+		switch prop.Name {
+		case "passable":
+			p.Passable, err = strconv.ParseBool(prop.Value)
+		case "opaque":
+			p.Opaque, err = strconv.ParseBool(prop.Value)
+		case "speed":
+			p.Speed, err = strconv.ParseFloat(prop.Value, 64)
+		case "recovery":
+			p.Recovery, err = strconv.ParseFloat(prop.Value, 64)
+		case "region":
+			p.Region = prop.Value
+		case "theme":
+			p.Theme = prop.Value
+		case "frame":
+			p.Frame = prop.Value
+		}
+		if err != nil {
+			return fmt.Errorf("Invalid tile property %v: %v", prop.Name, err)
+		}
+	}
+	return
+}
+
+func newTileItem(props TileProperties) *GridItem {
+	item := NewGridItem(props.Passable, props.Opaque, props.Frame, nil)
+	item.speed = props.Speed
+	item.recovery = props.Recovery
+	item.region = props.Region
+	item.theme = props.Theme
+	return item
+}
+
+func LoadTiledMap(path string) (out *TiledMap, err error) {
+	var data []byte
 	if data, err = ioutil.ReadFile(path); err != nil {
 		return
 	}
+	return parseTiledMap(data)
+}
+
+func parseTiledMap(data []byte) (out *TiledMap, err error) {
+	var (
+		m      *tmxgo.Map
+		floor  *TiledFloor
+		floors = map[string]*TiledFloor{}
+	)
 	if m, err = tmxgo.ParseMapString(string(data)); err != nil {
 		return
 	}
-	if tiles, err = m.TilesFromLayerName("ground"); err != nil {
+	out = &TiledMap{}
+	for _, layer := range m.Layers {
+		name, _ := floorLayer(layer.Name)
+		if floors[name] != nil {
+			continue
+		}
+		if floor, err = loadFloor(m, name); err != nil {
+			return
+		}
+		floors[name] = floor
+		out.Floors = append(out.Floors, floor)
+	}
+	for _, group := range m.ObjectGroups {
+		name, _ := floorLayer(group.Name)
+		if floor = floors[name]; floor == nil {
+			return nil, fmt.Errorf("Object layer %v is not on a floor", group.Name)
+		}
+		if err = addMarkers(m, floor, group); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// floorLayerName returns the full name of a layer on the named floor.
+func floorLayerName(floor, layer string) string {
+	if floor == "" {
+		return layer
+	}
+	return floor + "/" + layer
+}
+
+func hasLayer(m *tmxgo.Map, name string) bool {
+	for _, layer := range m.Layers {
+		if layer.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func loadFloor(m *tmxgo.Map, name string) (floor *TiledFloor, err error) {
+	var (
+		tiles []*tmxgo.Tile
+		props TileProperties
+		grid  *twodee.Grid
+		item  *GridItem
+		layer *MapLayer
+		x     int32
+		y     int32
+	)
+	if !hasLayer(m, floorLayerName(name, "ground")) {
+		return nil, fmt.Errorf("Floor %v has no ground layer", name)
+	}
+	if tiles, err = m.TilesFromLayerName(floorLayerName(name, "ground")); err != nil {
 		return
 	}
 	grid = twodee.NewGrid(m.Width, m.Height, 1.0)
+	floor = &TiledFloor{Name: name, Background: grid}
 	for x = 0; x < grid.Width; x++ {
 		for y = 0; y < grid.Height; y++ {
-			tile = tiles[y*grid.Width+x]
-			if tile != nil {
-				grid.Set(
-					x,
-					y,
-					NewGridItem(true, fmt.Sprintf("tiles_%02v", tile.Index), nil),
-				)
+			if tile := tiles[y*grid.Width+x]; tile != nil {
+				if props, err = tileProperties(tile, GroundProperties); err != nil {
+					return
+				}
+				grid.Set(x, y, newTileItem(props))
 			}
 		}
 	}
+	for _, layerName := range MapLayerNames {
+		if !hasLayer(m, floorLayerName(name, layerName)) {
+			continue
+		}
+		if tiles, err = m.TilesFromLayerName(floorLayerName(name, layerName)); err != nil {
+			return
+		}
+		layer = &MapLayer{
+			Name:   layerName,
+			Above:  layerName == "overlay",
+			width:  grid.Width,
+			frames: make([]string, grid.Width*grid.Height),
+		}
+		for x = 0; x < grid.Width; x++ {
+			for y = 0; y < grid.Height; y++ {
+				tile := tiles[y*grid.Width+x]
+				if tile == nil {
+					continue
+				}
+				if layerName == "walls" {
+					props = WallProperties
+				} else {
+					props = TileProperties{}
+				}
+				if props, err = tileProperties(tile, props); err != nil {
+					return
+				}
+				layer.frames[y*grid.Width+x] = props.Frame
+				item, _ = grid.Get(x, y).(*GridItem)
+				if layerName != "walls" || item == nil {
+					continue
+				}
+				// Walls decide how the tile underneath behaves.
+				item.passable = props.Passable
+				item.opaque = props.Opaque
+				item.speed = props.Speed
+				if props.Region != "" {
+					item.region = props.Region
+				}
+				if props.Theme != "" {
+					item.theme = props.Theme
+				}
+			}
+		}
+		floor.Layers = append(floor.Layers, layer)
+	}
 	return
 }
+
+// addMarkers adds the grid position of every object in group to floor, using
+// the center of the object's bounds.
+func addMarkers(m *tmxgo.Map, floor *TiledFloor, group tmxgo.ObjectGroup) error {
+	if m.TileWidth <= 0 || m.TileHeight <= 0 {
+		return fmt.Errorf("Map has invalid tile size %vx%v", m.TileWidth, m.TileHeight)
+	}
+	for _, obj := range group.Objects {
+		pt := Ivec2{
+			int32(math.Floor((float64(obj.X) + float64(obj.Width)/2) / float64(m.TileWidth))),
+			int32(math.Floor((float64(obj.Y) + float64(obj.Height)/2) / float64(m.TileHeight))),
+		}
+		if pt.X() < 0 || pt.Y() < 0 || pt.X() >= m.Width || pt.Y() >= m.Height {
+			return fmt.Errorf("Marker %v in layer %v is outside of the map", obj.Name, group.Name)
+		}
+		floor.Markers = append(floor.Markers, MapMarker{Kind: obj.Type, Name: obj.Name, Pos: pt})
+	}
+	return nil
+}
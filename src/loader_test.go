@@ -0,0 +1,235 @@
+// Copyright 2015 Pikkpoiss
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// testMap wraps tilesets and layers in a 4x3 map with 16 pixel tiles.
+func testMap(tilesets, body string) []byte {
+	return []byte(fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<map version="1.0" orientation="orthogonal" width="4" height="3" tilewidth="16" tileheight="16">
+%v
+%v
+</map>`, tilesets, body))
+}
+
+// testLayer encodes comma separated tile ids the way Tiled saves them.
+func testLayer(name, ids string) string {
+	var (
+		buf    bytes.Buffer
+		writer = zlib.NewWriter(&buf)
+	)
+	for _, field := range strings.Split(ids, ",") {
+		id, _ := strconv.ParseUint(strings.TrimSpace(field), 10, 32)
+		binary.Write(writer, binary.LittleEndian, uint32(id))
+	}
+	writer.Close()
+	return fmt.Sprintf(`<layer name="%v" width="4" height="3"><data encoding="base64" compression="zlib">%v</data></layer>`,
+		name, base64.StdEncoding.EncodeToString(buf.Bytes()))
+}
+
+const testTileset = `
+<tileset firstgid="1" name="Tiles">
+ <properties><property name="theme" value="crypt"/></properties>
+ <tile id="1">
+  <properties>
+   <property name="speed" value="0.5"/>
+   <property name="region" value="Hall"/>
+  </properties>
+ </tile>
+ <tile id="2">
+  <properties>
+   <property name="passable" value="true"/>
+   <property name="frame" value="curtain"/>
+  </properties>
+ </tile>
+</tileset>`
+
+func loadTestFloor(t *testing.T, data []byte) *TiledFloor {
+	m, err := parseTiledMap(data)
+	if err != nil {
+		t.Fatalf("Could not parse map: %v", err)
+	}
+	if len(m.Floors) != 1 {
+		t.Fatalf("Expected one floor, got %v", len(m.Floors))
+	}
+	return m.Floors[0]
+}
+
+func tileAt(floor *TiledFloor, x, y int32) *GridItem {
+	item, _ := floor.Background.Get(x, y).(*GridItem)
+	return item
+}
+
+func TestTileProperties(t *testing.T) {
+	var (
+		floor = loadTestFloor(t, testMap(testTileset, testLayer("ground", "1,2,1,1, 1,1,1,1, 1,1,1,0")))
+		plain = tileAt(floor, 0, 0)
+		slow  = tileAt(floor, 1, 0)
+	)
+	if plain == nil || !plain.Passable() || plain.Speed() != 1 || plain.Theme() != "crypt" || plain.Region() != "" {
+		t.Errorf("Expected passable crypt ground, got %+v", plain)
+	}
+	if plain.Frame() != "tiles_00" {
+		t.Errorf("Expected default frame, got %v", plain.Frame())
+	}
+	if slow.Speed() != 0.5 || slow.Region() != "Hall" || slow.Theme() != "crypt" {
+		t.Errorf("Expected tile properties over tileset properties, got %+v", slow)
+	}
+	if tileAt(floor, 3, 2) != nil {
+		t.Errorf("Expected no tile where the layer is empty")
+	}
+	if _, err := parseTiledMap(testMap(`<tileset firstgid="1"><properties>
+		<property name="speed" value="fast"/></properties></tileset>`, testLayer("ground", "1,1,1,1,1,1,1,1,1,1,1,1"))); err == nil {
+		t.Errorf("Expected an error for an invalid property")
+	}
+}
+
+func TestWallsLayer(t *testing.T) {
+	var (
+		floor = loadTestFloor(t, testMap(testTileset,
+			testLayer("ground", "1,1,1,1, 1,1,1,1, 1,1,1,1")+
+				testLayer("walls", "0,0,0,0, 0,1,3,0, 0,0,0,0")))
+		wall    = tileAt(floor, 1, 1)
+		curtain = tileAt(floor, 2, 1)
+	)
+	if wall.Passable() || !wall.Opaque() {
+		t.Errorf("Expected walls to block movement and sight")
+	}
+	if !curtain.Passable() || !curtain.Opaque() {
+		t.Errorf("Expected wall properties to let mobs through but not sight")
+	}
+	if !tileAt(floor, 0, 1).Passable() {
+		t.Errorf("Expected ground without walls to stay passable")
+	}
+	if len(floor.Layers) != 1 || floor.Layers[0].Frame(Ivec2{2, 1}) != "curtain" || floor.Layers[0].Frame(Ivec2{0, 0}) != "" {
+		t.Errorf("Expected walls to be drawn from their frames")
+	}
+}
+
+func TestMarkers(t *testing.T) {
+	var (
+		ground = testLayer("ground", "1,1,1,1,1,1,1,1,1,1,1,1")
+		floor  = loadTestFloor(t, testMap(testTileset, ground+`
+<objectgroup name="markers">
+ <object name="door" type="spawn" x="0" y="16" width="16" height="16"/>
+ <object name="exit" type="sink" x="48" y="32" width="16" height="16"/>
+ <object name="point" type="attraction" x="40" y="8"/>
+</objectgroup>`))
+		expected = []MapMarker{
+			{Kind: MarkerSpawn, Name: "door", Pos: Ivec2{0, 1}},
+			{Kind: MarkerSink, Name: "exit", Pos: Ivec2{3, 2}},
+			{Kind: MarkerAttraction, Name: "point", Pos: Ivec2{2, 0}},
+		}
+	)
+	if len(floor.Markers) != len(expected) {
+		t.Fatalf("Expected %v markers, got %v", len(expected), floor.Markers)
+	}
+	for i, marker := range floor.Markers {
+		if marker != expected[i] {
+			t.Errorf("Expected marker %+v, got %+v", expected[i], marker)
+		}
+	}
+	if _, err := parseTiledMap(testMap(testTileset, ground+`
+<objectgroup><object name="lost" type="spawn" x="64" y="0" width="16" height="16"/></objectgroup>`)); err == nil {
+		t.Errorf("Expected an error for a marker outside of the map")
+	}
+}
+
+func TestShippedMap(t *testing.T) {
+	g, err := NewGrid("resources/maps/map01.tmx")
+	if err != nil {
+		t.Fatalf("Could not load map: %v", err)
+	}
+	if spawns := g.Markers(MarkerSpawn); len(spawns) != 3 {
+		t.Errorf("Expected 3 spawns, got %v", spawns)
+	}
+	sinks := g.Markers(MarkerSink)
+	if len(sinks) != 1 {
+		t.Fatalf("Expected a sink, got %v", sinks)
+	}
+	if wall := g.GetBg(Ivec2{12, 1}); wall == nil || wall.Passable() {
+		t.Errorf("Expected the walls layer to divide the ground floor")
+	}
+	if speed := g.Speed(Ivec2{14, 9}); speed >= 1 {
+		t.Errorf("Expected cobwebs to slow mobs down, got speed %v", speed)
+	}
+	if recovery := g.Recovery(Ivec2{28, 3}); recovery <= 0 {
+		t.Errorf("Expected the rug to help mobs recover, got %v", recovery)
+	}
+	for _, marker := range []string{"emergency_exit", "gift_shop"} {
+		if len(g.Markers(marker)) == 0 {
+			t.Errorf("Expected an exit of type %v", marker)
+		}
+	}
+	if len(g.Floors()) != 2 {
+		t.Fatalf("Expected two floors, got %v", len(g.Floors()))
+	}
+	attractions := g.Markers(MarkerAttraction)
+	if len(attractions) == 0 || g.FloorAt(attractions[0]) != 1 {
+		t.Fatalf("Expected an attraction upstairs, got %v", attractions)
+	}
+	g.AddSink(sinks[0])
+	g.AddTarget(attractions[0])
+	g.CalculateDistances()
+	for _, pt := range g.Markers(MarkerSpawn) {
+		if g.Distance(pt) <= 0 {
+			t.Errorf("Expected exit to be reachable from %v", pt)
+		}
+		if g.Target(attractions[0]).Steps(pt) <= 0 {
+			t.Errorf("Expected the attraction upstairs to be reachable from %v", pt)
+		}
+	}
+}
+
+func TestPrefixedLayersAreFloors(t *testing.T) {
+	m, err := parseTiledMap(testMap(testTileset,
+		testLayer("Cellar/ground", "1,1,1,1, 1,1,1,1, 1,1,1,1")+
+			testLayer("Attic/ground", "0,0,0,0, 0,1,1,0, 0,0,0,0")+
+			testLayer("Attic/walls", "0,0,0,0, 0,0,1,0, 0,0,0,0")+`
+<objectgroup name="Cellar/markers"><object name="up" type="stairs" x="0" y="0" width="16" height="16"/></objectgroup>
+<objectgroup name="Attic/markers"><object name="up" type="stairs" x="16" y="16" width="16" height="16"/></objectgroup>`))
+	if err != nil {
+		t.Fatalf("Could not parse map: %v", err)
+	}
+	if len(m.Floors) != 2 || m.Floors[0].Name != "Cellar" || m.Floors[1].Name != "Attic" {
+		t.Fatalf("Expected a floor for each prefix, got %v", m.Floors)
+	}
+	if len(m.Floors[0].Layers) != 0 || len(m.Floors[1].Layers) != 1 || tileAt(m.Floors[1], 2, 1).Passable() {
+		t.Errorf("Expected the attic walls to stay on the attic")
+	}
+	if tileAt(m.Floors[1], 0, 0) != nil || tileAt(m.Floors[1], 1, 1) == nil {
+		t.Errorf("Expected the attic to only have ground where its layer does")
+	}
+	if marker := m.Floors[1].Markers[0]; marker.Kind != MarkerStairs || marker.Pos != (Ivec2{1, 1}) {
+		t.Errorf("Expected stairs in the attic, got %+v", marker)
+	}
+	if _, err = parseTiledMap(testMap(testTileset, testLayer("Empty/walls", "0,0,0,0,0,0,0,0,0,0,0,0"))); err == nil {
+		t.Errorf("Expected an error for a floor without ground")
+	}
+	if _, err = parseTiledMap(testMap(testTileset, testLayer("ground", "1,1,1,1,1,1,1,1,1,1,1,1")+`
+<objectgroup name="Roof/markers"><object name="up" type="stairs" x="0" y="0"/></objectgroup>`)); err == nil {
+		t.Errorf("Expected an error for markers on a missing floor")
+	}
+}
@@ -31,8 +31,12 @@ type Application struct {
 	layers           *twodee.Layers
 	Context          *twodee.Context
 	State            *State
+	Settings         *Settings
+	Input            *InputMap
 	GameEventHandler *twodee.GameEventHandler
 	AudioSystem      *AudioSystem
+	Achievements     *AchievementTracker
+	Medals           *MedalRecord
 	gameLayer        *GameLayer
 }
 
@@ -48,7 +52,22 @@ func NewApplication() (app *Application, err error) {
 		state            = NewState()
 		gameEventHandler = twodee.NewGameEventHandler(NumGameEventTypes)
 		audioSystem      *AudioSystem
+		settings         *Settings
+		input            *InputMap
+		medals           *MedalRecord
 	)
+	if settings, err = LoadSettings(ConfigPath(SettingsFile)); err != nil {
+		fmt.Printf("Using default settings: %v\n", err)
+		err = nil
+	}
+	if input, err = LoadInputMap(ConfigPath(KeyBindingsFile)); err != nil {
+		fmt.Printf("Using default key bindings: %v\n", err)
+		err = nil
+	}
+	if medals, err = LoadMedalRecord(ConfigPath(MedalsFile)); err != nil {
+		fmt.Printf("Starting a new medal record: %v\n", err)
+		err = nil
+	}
 	if context, err = twodee.NewContext(); err != nil {
 		return
 	}
@@ -67,7 +86,11 @@ func NewApplication() (app *Application, err error) {
 		layers:           layers,
 		Context:          context,
 		State:            state,
+		Settings:         settings,
+		Input:            input,
 		GameEventHandler: gameEventHandler,
+		Achievements:     NewAchievementTracker(gameEventHandler),
+		Medals:           medals,
 	}
 	if app.gameLayer, err = NewGameLayer(state, app); err != nil {
 		return
@@ -85,7 +108,12 @@ func NewApplication() (app *Application, err error) {
 		return
 	}
 	layers.Push(hudlayer)
-	if audioSystem, err = NewAudioSystem(app); err != nil {
+	if audioSystem, err = NewAudioSystem(
+		gameEventHandler,
+		NewSdlAudioBackend(),
+		settings,
+		app.Listener,
+	); err != nil {
 		return
 	}
 	app.AudioSystem = audioSystem
@@ -101,18 +129,71 @@ func (a *Application) Draw() {
 
 func (a *Application) Update(elapsed time.Duration) {
 	a.layers.Update(elapsed)
+	a.AudioSystem.Update(elapsed)
+}
+
+// Listener returns the area of the level currently in view.
+func (a *Application) Listener() twodee.Rectangle {
+	return a.gameLayer.level.Camera.WorldBounds
 }
 
 func (a *Application) Delete() {
 	a.layers.Delete()
 	a.Context.Delete()
 	a.AudioSystem.Delete()
+	a.Achievements.Delete()
 }
 
 func (a *Application) SetUiState(state UiState) {
 	a.gameLayer.SetUiState(state)
 }
 
+// RoomUnderCursor returns the room under the mouse on the floor in view.
+func (a *Application) RoomUnderCursor() *Room {
+	level := a.gameLayer.level
+	return level.RoomAt(level.GetMouse())
+}
+
+// Avatar returns the monster controlled by the player.
+func (a *Application) Avatar() *Avatar {
+	return a.gameLayer.level.Avatar
+}
+
+// ComboPreview returns the names of the combos the block being placed would
+// form.
+func (a *Application) ComboPreview() []string {
+	return a.gameLayer.level.ComboPreview
+}
+
+// Objectives returns the objectives of the level being played.
+func (a *Application) Objectives() []*Objective {
+	return a.gameLayer.level.Objectives
+}
+
+// ObjectiveText describes objective and its progress for the HUD.
+func (a *Application) ObjectiveText(objective *Objective) string {
+	level := a.gameLayer.level
+	return objective.Text(level.State, level.Stats())
+}
+
+// FloorName returns the name of the floor in view, or an empty string if the
+// level only has one floor.
+func (a *Application) FloorName() string {
+	level := a.gameLayer.level
+	if floors := level.Grid.Floors(); len(floors) > 1 {
+		if name := floors[level.Floor].Name; name != "" {
+			return name
+		}
+		return fmt.Sprintf("Floor %v", level.Floor+1)
+	}
+	return ""
+}
+
+// WinLevel ends the level being played as won.
+func (a *Application) WinLevel() {
+	a.gameLayer.level.Win()
+}
+
 func (a *Application) UnsetHighlights() {
 	a.gameLayer.UnsetHighlights()
 }
@@ -0,0 +1,68 @@
+// Copyright 2015 Pikkpoiss
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	MinEffectiveness     = 0.5 // Fraction of its fear a worn out block still gives.
+	RepairFactor         = 0.5 // Fraction of a block's cost a full repair costs.
+	UpkeepEvery          = time.Minute
+	HandymanCost         = 150
+	HandymanWage         = 10  // Geld per UpkeepEvery.
+	HandymanRepairPerSec = 1.0 // Health restored per second.
+)
+
+// Condition returns how well the placement works given its wear, between
+// MinEffectiveness and 1.
+func (p BlockPlacement) Condition() float64 {
+	if p.Block.Health <= 0 {
+		return 1
+	}
+	return MinEffectiveness + (1-MinEffectiveness)*math.Max(0, p.Health)/p.Block.Health
+}
+
+// Damaged returns true if the placement has lost any health.
+func (p BlockPlacement) Damaged() bool {
+	return p.Block.Health > 0 && p.Health < p.Block.Health
+}
+
+// RepairCost returns the Geld it takes to restore the placement to full
+// health.
+func (p BlockPlacement) RepairCost() int {
+	if !p.Damaged() {
+		return 0
+	}
+	missing := 1 - math.Max(0, p.Health)/p.Block.Health
+	return int(math.Ceil(RepairFactor * float64(p.Block.Cost) * missing))
+}
+
+// Wear takes the health lost from spending elapsed scaring, returning the
+// updated placement.
+func (p BlockPlacement) Wear(elapsed time.Duration) BlockPlacement {
+	if p.Block.Health > 0 {
+		p.Health -= p.Block.Wear * elapsed.Seconds()
+	}
+	return p
+}
+
+// Repair restores amount of health, returning the updated placement.
+func (p BlockPlacement) Repair(amount float64) BlockPlacement {
+	p.Health = math.Min(p.Block.Health, math.Max(0, p.Health)+amount)
+	return p
+}
@@ -0,0 +1,120 @@
+// Copyright 2015 Pikkpoiss
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"testing"
+	"time"
+)
+
+func TestCondition(t *testing.T) {
+	for _, ct := range []struct {
+		block    *Block
+		health   float64
+		expected float64
+	}{
+		{&SkellyBlock, SkellyBlock.Health, 1},
+		{&SkellyBlock, SkellyBlock.Health / 2, 1 - (1-MinEffectiveness)/2},
+		{&SkellyBlock, 0, MinEffectiveness},
+		{&SkellyBlock, -1, MinEffectiveness},
+		{&DoorBlock, 0, 1}, // Blocks without health never wear out.
+	} {
+		placement := BlockPlacement{Block: ct.block, Health: ct.health}
+		if condition := placement.Condition(); condition != ct.expected {
+			t.Errorf("Expected %v with %v health to be in condition %v, got %v", ct.block.Title, ct.health, ct.expected, condition)
+		}
+	}
+}
+
+func TestRepairCost(t *testing.T) {
+	var full = int(RepairFactor * float64(SkellyBlock.Cost))
+	for _, rt := range []struct {
+		health   float64
+		expected int
+	}{
+		{SkellyBlock.Health, 0},
+		{SkellyBlock.Health * 0.9, 1}, // Any damage costs something.
+		{0, full},
+		{-5, full}, // Overkill doesn't cost extra.
+	} {
+		placement := BlockPlacement{Block: &SkellyBlock, Health: rt.health}
+		if cost := placement.RepairCost(); cost != rt.expected {
+			t.Errorf("Expected repairing %v health to cost %v, got %v", rt.health, rt.expected, cost)
+		}
+	}
+}
+
+func TestRepair(t *testing.T) {
+	var placement = BlockPlacement{Block: &SkellyBlock, Health: -2}
+	if repaired := placement.Repair(1); repaired.Health != 1 {
+		t.Errorf("Expected repairs to start from zero, got %v", repaired.Health)
+	}
+	if repaired := placement.Repair(SkellyBlock.Health * 2); repaired.Health != SkellyBlock.Health {
+		t.Errorf("Expected repairs to stop at %v, got %v", SkellyBlock.Health, repaired.Health)
+	}
+	if placement.Health != -2 {
+		t.Errorf("Expected Repair to leave the original placement alone")
+	}
+}
+
+func TestWornOutBlocksStop(t *testing.T) {
+	var (
+		level = newTestLevel(newTestGrid(8, 8))
+		key   = addTestBlock(t, level, &SkellyBlock, Ivec2{4, 4})
+	)
+	placement := level.blocks[key]
+	placement.Health = SkellyBlock.Wear / 2
+	level.blocks[key] = placement
+	addTestMobs(level, CalmFear, mgl32.Vec2{3.5, 4.5})
+	level.updateBlocks(time.Second)
+	if placement = level.blocks[key]; !placement.Broken() {
+		t.Fatalf("Expected the block to wear out, has %v health", placement.Health)
+	}
+	if state := level.Grid.Get(key).State(); state != BlockDisabled {
+		t.Errorf("Expected a worn out block to be disabled, got state %v", state)
+	}
+	fear := level.Mobs[0].Fear
+	level.updateBlocks(time.Second)
+	if level.Mobs[0].Fear > fear {
+		t.Errorf("Expected a worn out block to stop scaring")
+	}
+	if state := level.Grid.Get(key).State(); state != BlockDisabled {
+		t.Errorf("Expected a worn out block to stay disabled, got state %v", state)
+	}
+}
+
+func TestUnpaidHandymenQuit(t *testing.T) {
+	for _, ht := range []struct {
+		geld     int
+		handymen int
+		left     int
+		geldLeft int
+	}{
+		{100, 2, 2, 100 - 2*HandymanWage},
+		{HandymanWage, 2, 0, HandymanWage},
+	} {
+		level := newTestLevel(newTestGrid(4, 4))
+		level.State.Geld = ht.geld
+		level.State.Handymen = ht.handymen
+		level.updateMaintenance(UpkeepEvery)
+		if level.State.Handymen != ht.left {
+			t.Errorf("Expected %v of %v handymen to stay with %v geld, got %v", ht.left, ht.handymen, ht.geld, level.State.Handymen)
+		}
+		if level.State.Geld != ht.geldLeft {
+			t.Errorf("Expected %v geld left, got %v", ht.geldLeft, level.State.Geld)
+		}
+	}
+}
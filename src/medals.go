@@ -0,0 +1,156 @@
+// Copyright 2015 Pikkpoiss
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	MedalsFile = "medals.json"
+	MaxMedals  = 3
+)
+
+// Par is the score a level expects from a good play through. Geld and Fear
+// should be beaten, Deaths and Time (in seconds) should not be exceeded. A
+// zero Time or Fear has no par.
+type Par struct {
+	Geld   int
+	Deaths int
+	Time   float64
+	Fear   float64
+}
+
+// Score is how a level was won.
+type Score struct {
+	Geld   int
+	Deaths int
+	Time   time.Duration
+	Fear   float64 // Average fear of visitors who made it out.
+}
+
+func NewScore(evt *GameOverEvent) Score {
+	return Score{
+		Geld:   evt.Geld,
+		Deaths: evt.Stats.Died,
+		Time:   evt.Stats.PlayTime,
+		Fear:   evt.Stats.AverageFear(),
+	}
+}
+
+// Beaten returns how many of the par values score meets.
+func (p Par) Beaten(score Score) int {
+	var beaten = 0
+	if score.Geld >= p.Geld {
+		beaten++
+	}
+	if score.Deaths <= p.Deaths {
+		beaten++
+	}
+	if p.Time == 0 || score.Time.Seconds() <= p.Time {
+		beaten++
+	}
+	if score.Fear >= p.Fear {
+		beaten++
+	}
+	return beaten
+}
+
+// Medals converts score into medals. Winning is worth one, meeting half of
+// the par values is worth two and meeting all of them is worth three.
+func (p Par) Medals(score Score) int {
+	switch beaten := p.Beaten(score); {
+	case beaten == 4:
+		return 3
+	case beaten >= 2:
+		return 2
+	}
+	return 1
+}
+
+// Text describes score against par for the win screen.
+func (p Par) Text(score Score) []string {
+	lines := []string{
+		fmt.Sprintf("Geld: %v (par %v)", score.Geld, p.Geld),
+		fmt.Sprintf("Deaths: %v (par %v)", score.Deaths, p.Deaths),
+		fmt.Sprintf("Time: %.0fs", score.Time.Seconds()),
+		fmt.Sprintf("Average fear: %.1f", score.Fear),
+	}
+	if p.Time > 0 {
+		lines[2] += fmt.Sprintf(" (par %.0fs)", p.Time)
+	}
+	if p.Fear > 0 {
+		lines[3] += fmt.Sprintf(" (par %.1f)", p.Fear)
+	}
+	return lines
+}
+
+// LevelResult is shown on the win screen.
+type LevelResult struct {
+	Profile string
+	Level   *LevelDef
+	Score   Score
+	Medals  int
+	Best    int // Most medals the profile ever won on the level, including these.
+}
+
+// MedalRecord keeps the most medals each profile has won on each level, by
+// profile and then level name.
+type MedalRecord struct {
+	Best map[string]map[string]int
+	path string
+}
+
+// LoadMedalRecord returns the medals saved at path, or an empty record if
+// nothing has been saved yet.
+func LoadMedalRecord(path string) (r *MedalRecord, err error) {
+	r = &MedalRecord{
+		Best: map[string]map[string]int{},
+		path: path,
+	}
+	if err = LoadJSON(path, r); err != nil || r.Best == nil {
+		r.Best = map[string]map[string]int{}
+	}
+	return
+}
+
+// Award records medals won by profile on level and returns the result to
+// show. The record is saved whenever it improves.
+func (r *MedalRecord) Award(profile string, level *LevelDef, score Score) (result *LevelResult, err error) {
+	if r.Best[profile] == nil {
+		r.Best[profile] = map[string]int{}
+	}
+	result = &LevelResult{
+		Profile: profile,
+		Level:   level,
+		Score:   score,
+		Medals:  level.Par.Medals(score),
+		Best:    r.Best[profile][level.Name],
+	}
+	if result.Medals > result.Best {
+		result.Best = result.Medals
+		r.Best[profile][level.Name] = result.Medals
+		err = r.Save()
+	}
+	return
+}
+
+func (r *MedalRecord) Save() error {
+	if r.path == "" {
+		return nil
+	}
+	return SaveJSON(r.path, r)
+}
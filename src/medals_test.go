@@ -0,0 +1,92 @@
+// Copyright 2015 Pikkpoiss
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMedalsAgainstPar(t *testing.T) {
+	var (
+		par   = Par{Geld: 500, Deaths: 1, Time: 120, Fear: 7}
+		score = Score{Geld: 600, Deaths: 0, Time: 100 * time.Second, Fear: 8}
+	)
+	if medals := par.Medals(score); medals != 3 {
+		t.Errorf("Expected 3 medals for beating par, got %v", medals)
+	}
+	score.Deaths = 2
+	score.Time = 200 * time.Second
+	if medals := par.Medals(score); medals != 2 {
+		t.Errorf("Expected 2 medals for meeting half of par, got %v", medals)
+	}
+	score.Geld = 100
+	if medals := par.Medals(score); medals != 1 {
+		t.Errorf("Expected 1 medal for winning, got %v", medals)
+	}
+}
+
+func TestMedalRecordKeepsBest(t *testing.T) {
+	var (
+		dir    string
+		level  = &LevelDef{Name: "Test", Par: Par{Geld: 500}}
+		record *MedalRecord
+		err    error
+	)
+	if dir, err = ioutil.TempDir("", "medals"); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, MedalsFile)
+	if record, err = LoadMedalRecord(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = record.Award(Profiles[0], level, Score{Geld: 500}); err != nil {
+		t.Fatal(err)
+	}
+	if record, err = LoadMedalRecord(path); err != nil {
+		t.Fatal(err)
+	}
+	result, err := record.Award(Profiles[0], level, Score{Geld: 0, Deaths: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Medals != 2 || result.Best != 3 {
+		t.Errorf("Expected 2 medals with a best of 3, got %v and %v", result.Medals, result.Best)
+	}
+}
+
+func TestMedalsArePerProfile(t *testing.T) {
+	var (
+		level  = &LevelDef{Name: "Test", Par: Par{Geld: 500}}
+		record = &MedalRecord{Best: map[string]map[string]int{}}
+	)
+	if _, err := record.Award(Profiles[0], level, Score{Geld: 500}); err != nil {
+		t.Fatal(err)
+	}
+	result, err := record.Award(Profiles[1], level, Score{Geld: 0, Deaths: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Best != result.Medals || result.Profile != Profiles[1] {
+		t.Errorf("Expected %v's best to be their own %v medals, got %v", Profiles[1], result.Medals, result.Best)
+	}
+	if best := record.Best[Profiles[0]][level.Name]; best != 3 {
+		t.Errorf("Expected %v to keep 3 medals, got %v", Profiles[0], best)
+	}
+}
@@ -18,11 +18,15 @@ import (
 	"../lib/twodee"
 	"fmt"
 	"image/color"
+	"math"
 	"time"
 )
 
 const (
 	ProgramCode int32 = iota
+	AudioCode
+	ControlsCode
+	ProfileCode
 )
 
 const (
@@ -30,12 +34,75 @@ const (
 	DebugCode
 	WinCode
 	LoseCode
+	AudioMenuCode
+	ControlsMenuCode
+	ProfileMenuCode
+	ControlsPageCode
+	ResetKeysCode
+	BackCode
 )
 
+const (
+	MasterVolumeCode int32 = iota
+	MusicVolumeCode
+	EffectVolumeCode
+	MusicMuteCode
+)
+
+const VolumeStep = 0.1
+
+// controlPages splits the rebinding screen up so each page fits on screen.
+var controlPages = [][]InputAction{
+	[]InputAction{
+		ActionNormalMode,
+		ActionDeleteMode,
+		ActionRotate,
+		ActionToggleDoor,
+		ActionToggleMusic,
+		ActionMenu,
+		ActionAdvance,
+	},
+	[]InputAction{
+		ActionBlock1,
+		ActionBlock2,
+		ActionBlock3,
+		ActionBlock4,
+		ActionBlock5,
+		ActionBlock6,
+		ActionBlock7,
+		ActionBlock8,
+		ActionBlock9,
+	},
+	[]InputAction{
+		ActionMenuUp,
+		ActionMenuDown,
+		ActionMenuSelect,
+		ActionFloorUp,
+		ActionFloorDown,
+		ActionRepair,
+		ActionHireHandyman,
+		ActionHireMinion,
+	},
+	[]InputAction{
+		ActionAvatarUp,
+		ActionAvatarDown,
+		ActionAvatarLeft,
+		ActionAvatarRight,
+		ActionBoo,
+		ActionChill,
+		ActionPhase,
+	},
+}
+
 type MenuLayer struct {
 	visible   bool
 	menu      *twodee.Menu
 	debugMenu *twodee.Menu
+	submenu   *twodee.Menu
+	page      int
+	rebinding InputAction
+	message   string
+	msgcache  *twodee.TextCache
 	text      *twodee.TextRenderer
 	regfont   *twodee.FontFace
 	cache     map[int]*twodee.TextCache
@@ -69,6 +136,9 @@ func NewMenuLayer(winb twodee.Rectangle, state *State, app *Application) (layer
 	menu, err = twodee.NewMenu([]twodee.MenuItem{
 		twodee.NewKeyValueMenuItem("Exit", ProgramCode, ExitCode),
 		twodee.NewKeyValueMenuItem("Debug", ProgramCode, DebugCode),
+		twodee.NewKeyValueMenuItem("Audio", ProgramCode, AudioMenuCode),
+		twodee.NewKeyValueMenuItem("Controls", ProgramCode, ControlsMenuCode),
+		twodee.NewKeyValueMenuItem("Profile", ProgramCode, ProfileMenuCode),
 	})
 	if err != nil {
 		return
@@ -92,6 +162,7 @@ func NewMenuLayer(winb twodee.Rectangle, state *State, app *Application) (layer
 		cache:     map[int]*twodee.TextCache{},
 		actcache:  twodee.NewTextCache(actfont),
 		hicache:   twodee.NewTextCache(hifont),
+		msgcache:  twodee.NewTextCache(hifont),
 		camera:    camera,
 		state:     state,
 		visible:   false,
@@ -109,6 +180,7 @@ func (ml *MenuLayer) Reset() (err error) {
 	}
 	ml.actcache.Clear()
 	ml.hicache.Clear()
+	ml.msgcache.Clear()
 	for _, v := range ml.cache {
 		v.Clear()
 	}
@@ -119,12 +191,16 @@ func (ml *MenuLayer) Delete() {
 	ml.text.Delete()
 	ml.actcache.Delete()
 	ml.hicache.Delete()
+	ml.msgcache.Delete()
 	for _, v := range ml.cache {
 		v.Delete()
 	}
 }
 
 func (ml *MenuLayer) CurrentMenu() *twodee.Menu {
+	if ml.submenu != nil {
+		return ml.submenu
+	}
 	if ml.state.Debug {
 		return ml.debugMenu
 	} else {
@@ -163,6 +239,13 @@ func (ml *MenuLayer) Render() {
 			ml.text.Draw(texture, 10, y, 1)
 		}
 	}
+	if ml.message != "" {
+		ml.msgcache.SetText(ml.message)
+		if texture = ml.msgcache.Texture; texture != nil {
+			y = y - 2*float32(texture.Height)
+			ml.text.Draw(texture, 10, y, 1)
+		}
+	}
 	ml.text.Unbind()
 }
 
@@ -171,18 +254,18 @@ func (ml *MenuLayer) Update(elapsed time.Duration) {
 
 func (ml *MenuLayer) HandleEvent(evt twodee.Event) bool {
 	if !ml.visible {
-		switch event := evt.(type) {
-		case *twodee.KeyEvent:
-			if event.Type != twodee.Press {
-				break
-			}
-			if event.Code == twodee.KeyEscape {
-				ml.CurrentMenu().Reset()
-				ml.visible = true
-			}
+		if ml.app.Input.Action(evt) == ActionMenu {
+			ml.CurrentMenu().Reset()
+			ml.visible = true
 		}
 		return true
 	}
+	if ml.rebinding != ActionNone {
+		if event, ok := evt.(*twodee.KeyEvent); ok && event.Type == twodee.Press {
+			ml.finishRebinding(event.Code)
+			return false
+		}
+	}
 	switch event := evt.(type) {
 	case *twodee.MouseButtonEvent:
 		if event.Type != twodee.Press {
@@ -219,26 +302,24 @@ func (ml *MenuLayer) HandleEvent(evt twodee.Event) bool {
 				}
 			}
 		}
-	case *twodee.KeyEvent:
-		if event.Type != twodee.Press {
-			break
-		}
-		switch event.Code {
-		case twodee.KeyEscape:
-			ml.visible = false
-			return false
-		case twodee.KeyUp:
-			ml.CurrentMenu().Prev()
-			return false
-		case twodee.KeyDown:
-			ml.CurrentMenu().Next()
-			return false
-		case twodee.KeyEnter:
-			if data := ml.CurrentMenu().Select(); data != nil {
-				ml.handleMenuItem(data)
-			}
-			return false
+	}
+	switch ml.app.Input.Action(evt) {
+	case ActionMenu:
+		ml.visible = false
+		ml.submenu = nil
+		ml.message = ""
+		return false
+	case ActionMenuUp:
+		ml.CurrentMenu().Prev()
+		return false
+	case ActionMenuDown:
+		ml.CurrentMenu().Next()
+		return false
+	case ActionMenuSelect:
+		if data := ml.CurrentMenu().Select(); data != nil {
+			ml.handleMenuItem(data)
 		}
+		return false
 	}
 	return true
 }
@@ -253,13 +334,182 @@ func (ml *MenuLayer) handleMenuItem(data *twodee.MenuItemData) {
 			ml.state.Debug = !ml.state.Debug
 			ml.visible = false
 		case WinCode:
-			ml.state.SplashState = SplashWin
+			ml.app.WinLevel()
 			ml.visible = false
 		case LoseCode:
 			ml.app.GameEventHandler.Enqueue(twodee.NewBasicGameEvent(PlayerLost))
 			ml.visible = false
+		case AudioMenuCode:
+			ml.showAudioMenu(0)
+		case ProfileMenuCode:
+			ml.showProfileMenu(0)
+		case ControlsMenuCode:
+			ml.page = 0
+			ml.showControlsMenu(0)
+		case ControlsPageCode:
+			ml.page = (ml.page + 1) % len(controlPages)
+			ml.showControlsMenu(0)
+		case ResetKeysCode:
+			ml.app.Input.ResetDefaults()
+			ml.saveKeys("Restored default keys")
+			ml.showControlsMenu(len(controlPages[ml.page]) + 1)
+		case BackCode:
+			ml.submenu = nil
+			ml.message = ""
+			ml.CurrentMenu().Reset()
 		}
+	case ControlsCode:
+		ml.rebinding = InputAction(data.Value)
+		ml.message = fmt.Sprintf("Press a key for %v (%v cancels)", ActionLabel(ml.rebinding), ml.app.Input.KeyName(ActionMenu))
+	case ProfileCode:
+		ml.app.Settings.Profile = Profiles[data.Value]
+		ml.message = fmt.Sprintf("Playing as %v", ml.app.Settings.Profile)
+		if err := ml.app.Settings.Save(); err != nil {
+			ml.message = fmt.Sprintf("Could not save settings: %v", err)
+		}
+		ml.showProfileMenu(int(data.Value))
+	case AudioCode:
+		var settings = ml.app.AudioSystem.Settings()
+		switch data.Value {
+		case MasterVolumeCode:
+			settings.MasterVolume = nextVolume(settings.MasterVolume)
+		case MusicVolumeCode:
+			settings.MusicVolume = nextVolume(settings.MusicVolume)
+		case EffectVolumeCode:
+			settings.EffectVolume = nextVolume(settings.EffectVolume)
+		case MusicMuteCode:
+			settings.MusicMuted = !settings.MusicMuted
+		}
+		if err := ml.app.AudioSystem.ApplySettings(); err != nil {
+			fmt.Printf("Could not save settings: %v\n", err)
+		}
+		ml.showAudioMenu(int(data.Value))
 	default:
 		fmt.Printf("Menu entry selection: %v\n", data)
 	}
 }
+
+// showAudioMenu rebuilds the audio menu so labels reflect current settings
+// and highlights the item at index.
+func (ml *MenuLayer) showAudioMenu(index int) {
+	var (
+		settings = ml.app.AudioSystem.Settings()
+		muted    = "On"
+		menu     *twodee.Menu
+		err      error
+	)
+	if settings.MusicMuted {
+		muted = "Off"
+	}
+	menu, err = twodee.NewMenu([]twodee.MenuItem{
+		twodee.NewKeyValueMenuItem(volumeLabel("Master volume", settings.MasterVolume), AudioCode, MasterVolumeCode),
+		twodee.NewKeyValueMenuItem(volumeLabel("Music volume", settings.MusicVolume), AudioCode, MusicVolumeCode),
+		twodee.NewKeyValueMenuItem(volumeLabel("Effects volume", settings.EffectVolume), AudioCode, EffectVolumeCode),
+		twodee.NewKeyValueMenuItem(fmt.Sprintf("Music: %v", muted), AudioCode, MusicMuteCode),
+		twodee.NewKeyValueMenuItem("Back", ProgramCode, BackCode),
+	})
+	if err != nil {
+		fmt.Printf("Could not build audio menu: %v\n", err)
+		return
+	}
+	if index > 0 && index < len(menu.Items()) {
+		menu.HighlightItem(menu.Items()[index])
+	}
+	ml.submenu = menu
+}
+
+// showProfileMenu rebuilds the profile menu so the active profile is marked
+// and highlights the item at index.
+func (ml *MenuLayer) showProfileMenu(index int) {
+	var (
+		items = []twodee.MenuItem{}
+		menu  *twodee.Menu
+		err   error
+	)
+	for i, profile := range Profiles {
+		if profile == ml.app.Settings.Profile {
+			profile += " (playing)"
+		}
+		items = append(items, twodee.NewKeyValueMenuItem(profile, ProfileCode, int32(i)))
+	}
+	items = append(items, twodee.NewKeyValueMenuItem("Back", ProgramCode, BackCode))
+	if menu, err = twodee.NewMenu(items); err != nil {
+		fmt.Printf("Could not build profile menu: %v\n", err)
+		return
+	}
+	if index > 0 && index < len(menu.Items()) {
+		menu.HighlightItem(menu.Items()[index])
+	}
+	ml.submenu = menu
+}
+
+func volumeLabel(label string, volume float64) string {
+	return fmt.Sprintf("%v: %v%%", label, int(math.Floor(volume*100+0.5)))
+}
+
+// nextVolume steps volume up, wrapping back around to silent.
+func nextVolume(volume float64) float64 {
+	volume = math.Floor((volume+VolumeStep)*10+0.5) / 10
+	if volume > 1 {
+		return 0
+	}
+	return volume
+}
+
+// showControlsMenu rebuilds the current page of the rebinding screen and
+// highlights the item at index.
+func (ml *MenuLayer) showControlsMenu(index int) {
+	var (
+		items = []twodee.MenuItem{}
+		menu  *twodee.Menu
+		err   error
+	)
+	for _, action := range controlPages[ml.page] {
+		items = append(items, twodee.NewKeyValueMenuItem(
+			fmt.Sprintf("%v: %v", ActionLabel(action), ml.app.Input.KeyName(action)),
+			ControlsCode,
+			int32(action),
+		))
+	}
+	items = append(items,
+		twodee.NewKeyValueMenuItem("More...", ProgramCode, ControlsPageCode),
+		twodee.NewKeyValueMenuItem("Reset to defaults", ProgramCode, ResetKeysCode),
+		twodee.NewKeyValueMenuItem("Back", ProgramCode, BackCode),
+	)
+	if menu, err = twodee.NewMenu(items); err != nil {
+		fmt.Printf("Could not build controls menu: %v\n", err)
+		return
+	}
+	if index > 0 && index < len(menu.Items()) {
+		menu.HighlightItem(menu.Items()[index])
+	}
+	ml.submenu = menu
+}
+
+// finishRebinding assigns key to the action picked on the rebinding screen,
+// refusing keys which are already in use. The menu key cancels.
+func (ml *MenuLayer) finishRebinding(key twodee.KeyCode) {
+	var action = ml.rebinding
+	ml.rebinding = ActionNone
+	if key == ml.app.Input.Key(ActionMenu) {
+		ml.message = ""
+		return
+	}
+	if conflict, ok := ml.app.Input.Bind(action, key); !ok {
+		ml.message = fmt.Sprintf("%v is already used for %v", KeyName(key), ActionLabel(conflict))
+		return
+	}
+	ml.saveKeys("")
+	for i, a := range controlPages[ml.page] {
+		if a == action {
+			ml.showControlsMenu(i)
+		}
+	}
+}
+
+func (ml *MenuLayer) saveKeys(message string) {
+	ml.message = message
+	if err := ml.app.Input.Save(); err != nil {
+		ml.message = fmt.Sprintf("Could not save keys: %v", err)
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright 2015 Pikkpoiss
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"../lib/twodee"
+	"fmt"
+	"github.com/go-gl/mathgl/mgl32"
+	"time"
+)
+
+const (
+	MaxMinions       = 6
+	MaxPatrolPoints  = 8
+	MinionCost       = 100
+	MinionWage       = 15 // Geld per UpkeepEvery.
+	MinionSpeed      = 1.5
+	MinionRange      = 2.0
+	MinionFearPerSec = 3.0
+	MinionScareFor   = 2 * time.Second
+	MinionCooldown   = 5 * time.Second
+)
+
+// Minion is a hired monster which walks a patrol route drawn by the player,
+// jumping out at visitors it meets.
+type Minion struct {
+	*twodee.AnimatingEntity
+	Pos      mgl32.Vec2
+	Route    []Ivec2
+	fields   []*DistanceField
+	next     int
+	scaring  time.Duration
+	cooldown time.Duration
+	left     bool
+}
+
+func NewMinion(sheet *twodee.Spritesheet, route []Ivec2, grid *Grid) *Minion {
+	var (
+		frame = sheet.GetFrame("skeleton01_00")
+		m     = &Minion{
+			AnimatingEntity: twodee.NewAnimatingEntity(
+				0, 0,
+				frame.Width, frame.Height,
+				0.0,
+				twodee.Step10Hz,
+				SkeletonAnimations[BlockNormal],
+			),
+			Pos: mgl32.Vec2{
+				grid.grid.InversePosition(route[0].X()),
+				grid.grid.InversePosition(route[0].Y()),
+			},
+			Route:  route,
+			fields: make([]*DistanceField, len(route)),
+		}
+	)
+	return m
+}
+
+// Repath forgets the way between the stops of the patrol after the layout of
+// the house has changed. Each leg is worked out again when the minion next
+// walks it.
+func (m *Minion) Repath() {
+	for i := range m.fields {
+		m.fields[i] = nil
+	}
+}
+
+// field returns the way to stop i of the patrol, working it out if needed.
+func (m *Minion) field(i int, grid *Grid) *DistanceField {
+	if m.fields[i] == nil {
+		m.fields[i] = grid.NewPathField(m.Route[i])
+	}
+	return m.fields[i]
+}
+
+// Ready returns true if the minion may jump out at visitors again.
+func (m *Minion) Ready() bool {
+	return m.scaring <= 0 && m.cooldown <= 0
+}
+
+// Scaring returns true while the minion is frightening visitors.
+func (m *Minion) Scaring() bool {
+	return m.scaring > 0
+}
+
+// Scare starts the minion frightening visitors for MinionScareFor.
+func (m *Minion) Scare() {
+	m.scaring = MinionScareFor
+	m.SetFrames(SkeletonAnimations[BlockScaring])
+}
+
+func (m *Minion) Update(elapsed time.Duration, grid *Grid) {
+	m.AnimatingEntity.Update(elapsed)
+	switch {
+	case m.scaring > 0:
+		if m.scaring -= elapsed; m.scaring <= 0 {
+			m.cooldown = MinionCooldown
+			m.SetFrames(SkeletonAnimations[BlockNormal])
+		}
+		return // Minions stand still while scaring.
+	case m.cooldown > 0:
+		m.cooldown -= elapsed
+	}
+	m.patrol(elapsed, grid)
+}
+
+// patrol walks the minion towards the next stop on its route, moving on to
+// the following stop once it gets there or if there is no way through.
+func (m *Minion) patrol(elapsed time.Duration, grid *Grid) {
+	var (
+		pt   = grid.WorldToGrid(m.Pos)
+		step = float32(elapsed.Seconds()) * MinionSpeed * float32(grid.Speed(pt))
+	)
+	for i := 0; i < len(m.Route); i++ {
+		if pt != m.Route[m.next] {
+			if dest, _, ok := grid.GetNextStep(m.Pos, m.field(m.next, grid)); ok {
+				if grid.IsStairs(pt, grid.WorldToGrid(dest)) {
+					m.Pos = dest
+					return
+				}
+				toDest := dest.Sub(m.Pos)
+				m.left = toDest.X() < 0
+				m.Pos = m.Pos.Add(toDest.Normalize().Mul(step))
+				return
+			}
+		}
+		m.next = (m.next + 1) % len(m.Route)
+	}
+}
+
+func (m *Minion) SpriteConfig(sheet *twodee.Spritesheet) twodee.SpriteConfig {
+	var (
+		frame          = sheet.GetFrame(fmt.Sprintf("skeleton01_%02v", m.Frame()))
+		scaleX float32 = 1.0
+	)
+	if m.left {
+		scaleX = -1.0
+	}
+	return twodee.SpriteConfig{
+		View: twodee.ModelViewConfig{
+			m.Pos.X(), m.Pos.Y() + frame.Height/4.0, 0.0,
+			0, 0, 0,
+			scaleX, 1.0, 1.0,
+		},
+		Frame: frame.Frame,
+	}
+}
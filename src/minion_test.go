@@ -0,0 +1,139 @@
+// Copyright 2015 Pikkpoiss
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"testing"
+	"time"
+)
+
+// newTestMinion returns a minion standing at the start of route, without
+// the sprites NewMinion needs.
+func newTestMinion(route ...Ivec2) *Minion {
+	return &Minion{
+		Pos:    mgl32.Vec2{float32(route[0].X()) + 0.5, float32(route[0].Y()) + 0.5},
+		Route:  route,
+		fields: make([]*DistanceField, len(route)),
+	}
+}
+
+func TestPatrolLoops(t *testing.T) {
+	var (
+		grid   = newTestGrid(8, 3)
+		minion = newTestMinion(Ivec2{1, 1}, Ivec2{6, 1})
+		stops  []int
+	)
+	for i := 0; i < 200 && len(stops) < 4; i++ {
+		minion.Update(100*time.Millisecond, grid)
+		if len(stops) == 0 || stops[len(stops)-1] != minion.next {
+			stops = append(stops, minion.next)
+		}
+	}
+	if len(stops) < 4 {
+		t.Fatalf("Expected the minion to walk its route twice, got stops %v", stops)
+	}
+	for i, stop := range stops {
+		if stop != (i+1)%2 {
+			t.Errorf("Expected the minion to head for stop %v, got %v", (i+1)%2, stop)
+		}
+	}
+}
+
+func TestRepathWaitsForTheNextWalk(t *testing.T) {
+	var (
+		grid   = newTestGrid(8, 3)
+		minion = newTestMinion(Ivec2{1, 1}, Ivec2{6, 1}, Ivec2{6, 0})
+	)
+	minion.Update(100*time.Millisecond, grid)
+	minion.Repath()
+	for i, field := range minion.fields {
+		if field != nil {
+			t.Errorf("Expected Repath to forget the way to stop %v", i)
+		}
+	}
+	minion.Update(100*time.Millisecond, grid)
+	for i, field := range minion.fields {
+		if (i == minion.next) != (field != nil) {
+			t.Errorf("Expected only the way to stop %v to be worked out, stop %v has %v", minion.next, i, field)
+		}
+	}
+}
+
+func TestMinionScareAndCooldown(t *testing.T) {
+	var (
+		grid   = newTestGrid(8, 3)
+		minion = newTestMinion(Ivec2{1, 1}, Ivec2{6, 1})
+	)
+	if !minion.Ready() {
+		t.Fatalf("Expected a new minion to be ready")
+	}
+	minion.Scare()
+	pos := minion.Pos
+	minion.Update(MinionScareFor/2, grid)
+	if !minion.Scaring() || minion.Ready() {
+		t.Errorf("Expected the minion to still be scaring")
+	}
+	if minion.Pos != pos {
+		t.Errorf("Expected the minion to stand still while scaring")
+	}
+	minion.Update(MinionScareFor/2, grid)
+	if minion.Scaring() || minion.Ready() {
+		t.Errorf("Expected the minion to cool down after scaring")
+	}
+	minion.Update(MinionCooldown, grid)
+	if !minion.Ready() {
+		t.Errorf("Expected the minion to be ready after cooling down")
+	}
+}
+
+func TestMinionsScareVisitors(t *testing.T) {
+	var level = newTestLevel(newTestGrid(12, 3))
+	level.Minions = []*Minion{newTestMinion(Ivec2{1, 1}, Ivec2{2, 1})}
+	addTestMobs(level, CalmFear, mgl32.Vec2{2.5, 1.5}, mgl32.Vec2{10.5, 1.5})
+	level.updateMinions(time.Second)
+	if !level.Minions[0].Scaring() {
+		t.Errorf("Expected the minion to jump out at the visitor")
+	}
+	if level.Mobs[0].Fear <= CalmFear {
+		t.Errorf("Expected the visitor in range to be scared")
+	}
+	if level.Mobs[1].Fear != CalmFear {
+		t.Errorf("Expected the visitor out of range to be left alone")
+	}
+}
+
+func TestMinionWages(t *testing.T) {
+	for _, wt := range []struct {
+		geld     int
+		left     int
+		geldLeft int
+	}{
+		{100, 2, 100 - 2*MinionWage},
+		{MinionWage, 0, MinionWage},
+	} {
+		level := newTestLevel(newTestGrid(8, 3))
+		level.Minions = []*Minion{newTestMinion(Ivec2{1, 1}), newTestMinion(Ivec2{2, 1})}
+		level.State.Minions = len(level.Minions)
+		level.State.Geld = wt.geld
+		level.updateMaintenance(UpkeepEvery)
+		if len(level.Minions) != wt.left || level.State.Minions != wt.left {
+			t.Errorf("Expected %v minions to stay with %v geld, got %v", wt.left, wt.geld, len(level.Minions))
+		}
+		if level.State.Geld != wt.geldLeft {
+			t.Errorf("Expected %v geld left, got %v", wt.geldLeft, level.State.Geld)
+		}
+	}
+}
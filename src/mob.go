@@ -18,6 +18,7 @@ import (
 	"../lib/twodee"
 	"fmt"
 	"github.com/go-gl/mathgl/mgl32"
+	"math"
 	"time"
 )
 
@@ -37,12 +38,23 @@ var MobAnimations = map[MobState][]int{
 
 type Mob struct {
 	*twodee.AnimatingEntity
+	Id             int
 	State          MobState
 	Speed          float32
+	Kind           *MobKind
 	Fear           float64
+	Tolerance      float64 // Fraction of incoming fear the mob shrugs off.
+	Temperament    *Temperament
+	Timeline       FearTimeline
+	Exposure       map[*Block]float64 // Fear taken from each kind of block.
+	sinceScare     time.Duration
+	screamed       bool
+	screamChain    int // Number of screams which led to this mob's scream.
 	Enabled        bool
 	PendingDisable bool
 	Pos            mgl32.Vec2
+	Itinerary      []Ivec2 // Attractions to visit before heading for the exit.
+	Exit           Ivec2   // Exit the mob is currently heading for.
 }
 
 func NewMob(sheet *twodee.Spritesheet) *Mob {
@@ -57,15 +69,32 @@ func NewMob(sheet *twodee.Spritesheet) *Mob {
 			twodee.Step10Hz,
 			MobAnimations[Walking|Right],
 		),
-		Fear: 1.0,
+		Fear:        CalmFear,
+		Kind:        &VisitorKind,
+		Temperament: &DefaultTemperament,
+		Exposure:    map[*Block]float64{},
 	}
 }
 
 func (m *Mob) Update(elapsed time.Duration, level *Level) {
 	m.AnimatingEntity.Update(elapsed)
+	m.updateFear(elapsed, level)
 	m.moveTowardExit(elapsed, level)
 }
 
+// updateFear lets the mob calm down and records its fear in the timeline.
+func (m *Mob) updateFear(elapsed time.Duration, level *Level) {
+	var recovery = level.Grid.Recovery(level.Grid.WorldToGrid(m.Pos))
+	m.sinceScare += elapsed
+	m.Fear = m.Temperament.Decay(m.Fear, elapsed, m.sinceScare, recovery)
+	m.Timeline.Record(elapsed, m.Temperament.SampleEvery, m.Fear)
+}
+
+// Review returns the fear the mob remembers from its visit.
+func (m *Mob) Review() float64 {
+	return m.Temperament.Review(&m.Timeline, m.Fear)
+}
+
 func (m *Mob) moveTowardExit(elapsed time.Duration, level *Level) {
 	var (
 		dest     mgl32.Vec2
@@ -73,13 +102,18 @@ func (m *Mob) moveTowardExit(elapsed time.Duration, level *Level) {
 		pct      = float32(elapsed) / float32(time.Second)
 		gridDist mgl32.Vec2
 		goalDist int32
-		stepDist = pct * m.Speed
+		terrain  = float32(level.Grid.Speed(level.Grid.WorldToGrid(m.Pos)) * level.Avatar.Chilling(m.Pos))
+		stepDist = pct * m.Speed * terrain
 	)
-	if dest, goalDist, ok = level.Grid.GetNextStepToSink(m.Pos); !ok {
+	if dest, goalDist, ok = m.nextStep(level); !ok {
+		return
+	}
+	if level.Grid.IsStairs(level.Grid.WorldToGrid(m.Pos), level.Grid.WorldToGrid(dest)) {
+		m.Pos = dest
 		return
 	}
 	gridDist = dest.Sub(m.Pos)
-	if goalDist == 1 && gridDist.Len() < stepDist+0.5 {
+	if len(m.Itinerary) == 0 && goalDist == 1 && gridDist.Len() < stepDist+0.5 {
 		m.PendingDisable = true
 	}
 	if gridDist.X() > 0 {
@@ -90,7 +124,38 @@ func (m *Mob) moveTowardExit(elapsed time.Duration, level *Level) {
 	m.Pos = m.Pos.Add(gridDist.Normalize().Mul(stepDist))
 }
 
-func (m *Mob) Activate(pos mgl32.Vec2, speed float32) {
+// nextStep returns the next tile on the way to the mob's next attraction, or
+// on the way to its chosen exit once it has seen them all. Attractions which have
+// been removed or can't be reached are skipped.
+func (m *Mob) nextStep(level *Level) (mgl32.Vec2, int32, bool) {
+	for len(m.Itinerary) > 0 {
+		var (
+			target = m.Itinerary[0]
+			field  = level.Grid.Target(target)
+			pt     = level.Grid.WorldToGrid(m.Pos)
+		)
+		if field != nil && (pt == target || field.Steps(pt) == 0) {
+			level.VisitAttraction(m)
+		} else if field != nil {
+			if dest, dist, ok := level.Grid.GetNextStep(m.Pos, field); ok {
+				return dest, dist, ok
+			}
+		}
+		m.Itinerary = m.Itinerary[1:]
+	}
+	exit, ok := level.ChooseExit(m)
+	if !ok {
+		return mgl32.Vec2{}, 0, false
+	}
+	m.Exit = exit.Pos
+	return level.Grid.GetNextStep(m.Pos, level.Grid.SinkField(exit.Pos))
+}
+
+func (m *Mob) Activate(id int, kind *MobKind, pos mgl32.Vec2, speed float32, itinerary []Ivec2) {
+	m.Id = id
+	m.Kind = kind
+	m.Temperament = kind.Temperament
+	m.Itinerary = itinerary
 	m.Enabled = true
 	m.PendingDisable = false
 	m.Pos = pos
@@ -101,12 +166,20 @@ func (m *Mob) Activate(pos mgl32.Vec2, speed float32) {
 func (m *Mob) Disable() {
 	m.Enabled = false
 	m.PendingDisable = false
-	m.Fear = 1.0
+	m.Fear = CalmFear
+	m.Tolerance = 0
+	m.Timeline.Reset()
+	m.sinceScare = 0
+	m.screamed = false
+	m.screamChain = 0
+	for block := range m.Exposure {
+		delete(m.Exposure, block)
+	}
 }
 
 func (m *Mob) AddSpriteConfig(sheet *twodee.Spritesheet, config []twodee.SpriteConfig) []twodee.SpriteConfig {
 	var (
-		frame               = sheet.GetFrame(fmt.Sprintf("human01_%02d", m.Frame()))
+		frame               = sheet.GetFrame(fmt.Sprintf("%v_%02d", m.Kind.Sprite, m.Frame()))
 		scaleX      float32 = 1.0
 		view        twodee.ModelViewConfig
 		overlayview twodee.ModelViewConfig
@@ -117,7 +190,7 @@ func (m *Mob) AddSpriteConfig(sheet *twodee.Spritesheet, config []twodee.SpriteC
 	view = twodee.ModelViewConfig{
 		m.Pos.X(), m.Pos.Y() + frame.Height/4.0, 0.0,
 		0, 0, 0,
-		scaleX, 1.0, 1.0,
+		scaleX * m.Kind.Scale, m.Kind.Scale, 1.0,
 	}
 	overlayview = twodee.ModelViewConfig{
 		m.Pos.X(), m.Pos.Y() + frame.Height/4.0 - 0.01, 0.0,
@@ -169,9 +242,27 @@ func (m *Mob) setState(state MobState) {
 	}
 }
 
-// IncreaseFear increments the mob's fear counter and returns a bool indicating
-// whether the mob is still alive or has passed away from fright.
+// Scare frightens the mob with a block, which works less well the more the
+// mob has already seen of that kind of block. Returns false if the mob was
+// scared to death.
+func (m *Mob) Scare(block *Block, fear float64) bool {
+	if fear > 0 {
+		exposure := m.Exposure[block]
+		m.Exposure[block] = exposure + fear
+		fear = m.Temperament.Habituate(fear, exposure)
+	}
+	return m.IncreaseFear(fear)
+}
+
+// IncreaseFear increments the mob's fear counter, less whatever the mob has
+// grown used to, and returns a bool indicating whether the mob is still alive
+// or has passed away from fright.
 func (m *Mob) IncreaseFear(fear float64) bool {
+	if fear > 0 {
+		m.sinceScare = 0
+	}
+	fear, m.Tolerance = m.Temperament.Absorb(fear, m.Tolerance)
 	m.Fear += fear
+	m.Timeline.Peak = math.Max(m.Timeline.Peak, m.Fear)
 	return m.Fear < 10
 }
@@ -0,0 +1,192 @@
+// Copyright 2015 Pikkpoiss
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+type ObjectiveStatus int32
+
+const (
+	ObjectivePending ObjectiveStatus = iota
+	ObjectiveMet
+	ObjectiveMissed
+)
+
+// Kinds of objective. Every goal has to be complete to win a level, breaking
+// any limit loses it.
+const (
+	GoalGeld     = "geld"       // Have Target Geld.
+	GoalRating   = "rating"     // Hold a rating of at least Target for Duration.
+	GoalSurvive  = "survive"    // Keep the house open for Duration.
+	GoalVisitors = "visitors"   // See Target visitors leave.
+	GoalThrills  = "thrills"    // See Target visitors leave with fear of at least ThrillFear.
+	LimitRating  = "min_rating" // Lose once the rating drops to Target.
+	LimitDeaths  = "max_deaths" // Lose once more than Target visitors die.
+	LimitTime    = "time_limit" // Lose if the goals aren't met within Duration.
+)
+
+// ObjectiveDef is an objective as written in the levels file. Durations are
+// in seconds.
+type ObjectiveDef struct {
+	Kind     string
+	Target   float64
+	Duration float64
+}
+
+func (d ObjectiveDef) duration() time.Duration {
+	return time.Duration(d.Duration * float64(time.Second))
+}
+
+// Validate returns an error if the objective can't be used, either because
+// its kind is unknown or it is missing the Target or Duration it needs.
+func (d ObjectiveDef) Validate() error {
+	switch d.Kind {
+	case GoalGeld, GoalVisitors, GoalThrills:
+		if d.Target <= 0 {
+			return fmt.Errorf("Objective %v needs a Target above 0", d.Kind)
+		}
+	case GoalRating:
+		if d.Target <= 0 || d.Duration <= 0 {
+			return fmt.Errorf("Objective %v needs a Target and Duration above 0", d.Kind)
+		}
+	case GoalSurvive, LimitTime:
+		if d.Duration <= 0 {
+			return fmt.Errorf("Objective %v needs a Duration above 0", d.Kind)
+		}
+	case LimitRating, LimitDeaths:
+		if d.Target < 0 {
+			return fmt.Errorf("Objective %v needs a Target of at least 0", d.Kind)
+		}
+	default:
+		return fmt.Errorf("Unknown objective %v", d.Kind)
+	}
+	return nil
+}
+
+var DefaultObjectives = []ObjectiveDef{
+	{Kind: LimitRating, Target: FAIL_RATING},
+	{Kind: GoalRating, Target: WIN_RATING, Duration: WIN_DURATION.Seconds()},
+}
+
+// Objective tracks the progress of one objective during a play through.
+type Objective struct {
+	ObjectiveDef
+	Status   ObjectiveStatus
+	Progress float64 // Between 0 and 1.
+	held     time.Duration
+}
+
+func NewObjectives(defs []ObjectiveDef) (objectives []*Objective) {
+	for _, def := range defs {
+		objectives = append(objectives, &Objective{ObjectiveDef: def})
+	}
+	return
+}
+
+// IsGoal returns true for objectives which have to be completed, rather than
+// limits which must not be broken.
+func (o *Objective) IsGoal() bool {
+	switch o.Kind {
+	case LimitRating, LimitDeaths, LimitTime:
+		return false
+	}
+	return true
+}
+
+// Update works out the progress of the objective, returning true if its
+// status has just changed. Objectives never change status twice.
+func (o *Objective) Update(elapsed time.Duration, state *State, stats Stats) bool {
+	if o.Status != ObjectivePending {
+		return false
+	}
+	var (
+		done   bool
+		failed bool
+	)
+	switch o.Kind {
+	case GoalGeld:
+		o.Progress = float64(state.Geld) / o.Target
+		done = o.Progress >= 1
+	case GoalRating:
+		if float64(state.Rating) >= o.Target {
+			o.held += elapsed
+		} else {
+			o.held = 0
+		}
+		o.Progress = o.held.Seconds() / o.Duration
+		done = o.held >= o.duration()
+	case GoalSurvive:
+		o.Progress = stats.PlayTime.Seconds() / o.Duration
+		done = stats.PlayTime >= o.duration()
+	case GoalVisitors:
+		o.Progress = float64(stats.Exited) / o.Target
+		done = o.Progress >= 1
+	case GoalThrills:
+		o.Progress = float64(stats.Thrilled) / o.Target
+		done = o.Progress >= 1
+	case LimitRating:
+		failed = float64(state.Rating) <= o.Target
+	case LimitDeaths:
+		o.Progress = float64(stats.Died) / math.Max(o.Target, 1)
+		failed = float64(stats.Died) > o.Target
+	case LimitTime:
+		o.Progress = stats.PlayTime.Seconds() / o.Duration
+		failed = stats.PlayTime >= o.duration()
+	}
+	o.Progress = math.Min(1, o.Progress)
+	switch {
+	case failed:
+		o.Status = ObjectiveMissed
+	case done:
+		o.Status = ObjectiveMet
+	default:
+		return false
+	}
+	return true
+}
+
+// Text describes the objective and how far along it is.
+func (o *Objective) Text(state *State, stats Stats) string {
+	var text string
+	switch o.Kind {
+	case GoalGeld:
+		text = fmt.Sprintf("Earn %v Geld: %v", o.Target, state.Geld)
+	case GoalRating:
+		text = fmt.Sprintf("Hold rating %v for %vs: %.0fs", o.Target, o.Duration, o.held.Seconds())
+	case GoalSurvive:
+		text = fmt.Sprintf("Stay open %vs: %.0fs", o.Duration, stats.PlayTime.Seconds())
+	case GoalVisitors:
+		text = fmt.Sprintf("Visitors: %v/%v", stats.Exited, o.Target)
+	case GoalThrills:
+		text = fmt.Sprintf("Thrilled visitors: %v/%v", stats.Thrilled, o.Target)
+	case LimitRating:
+		text = fmt.Sprintf("Keep rating above %v", o.Target)
+	case LimitDeaths:
+		text = fmt.Sprintf("Deaths: %v/%v allowed", stats.Died, o.Target)
+	case LimitTime:
+		text = fmt.Sprintf("Time left: %.0fs", math.Max(0, o.Duration-stats.PlayTime.Seconds()))
+	}
+	switch o.Status {
+	case ObjectiveMet:
+		text += " (done)"
+	case ObjectiveMissed:
+		text += " (failed)"
+	}
+	return text
+}
@@ -0,0 +1,91 @@
+// Copyright 2015 Pikkpoiss
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestObjectivesChangeStatusOnce(t *testing.T) {
+	var (
+		state     = NewState()
+		objective = NewObjectives([]ObjectiveDef{{Kind: GoalRating, Target: 8, Duration: 2}})[0]
+		changes   = 0
+	)
+	state.Rating = 8
+	for i := 0; i < 5; i++ {
+		if objective.Update(time.Second, state, Stats{}) {
+			changes++
+		}
+	}
+	if objective.Status != ObjectiveMet || changes != 1 {
+		t.Errorf("Expected objective met once, got status %v after %v changes", objective.Status, changes)
+	}
+}
+
+func TestLimitsFail(t *testing.T) {
+	var (
+		state      = NewState()
+		objectives = NewObjectives([]ObjectiveDef{
+			{Kind: LimitDeaths, Target: 1},
+			{Kind: LimitTime, Duration: 60},
+		})
+		stats = Stats{Died: 1, PlayTime: 30 * time.Second}
+	)
+	for _, objective := range objectives {
+		objective.Update(time.Second, state, stats)
+		if objective.IsGoal() || objective.Status != ObjectivePending {
+			t.Fatalf("Expected %v to be a pending limit", objective.Kind)
+		}
+	}
+	stats.Died = 2
+	stats.PlayTime = time.Minute
+	for _, objective := range objectives {
+		if !objective.Update(time.Second, state, stats) || objective.Status != ObjectiveMissed {
+			t.Errorf("Expected %v to fail", objective.Kind)
+		}
+	}
+}
+
+func TestValidateObjectives(t *testing.T) {
+	for _, vt := range []struct {
+		def ObjectiveDef
+		ok  bool
+	}{
+		{ObjectiveDef{Kind: GoalGeld, Target: 100}, true},
+		{ObjectiveDef{Kind: GoalGeld}, false},
+		{ObjectiveDef{Kind: GoalVisitors, Target: -1}, false},
+		{ObjectiveDef{Kind: GoalThrills}, false},
+		{ObjectiveDef{Kind: GoalRating, Target: 8, Duration: 5}, true},
+		{ObjectiveDef{Kind: GoalRating, Target: 8}, false},
+		{ObjectiveDef{Kind: GoalRating, Duration: 5}, false},
+		{ObjectiveDef{Kind: GoalSurvive, Duration: 60}, true},
+		{ObjectiveDef{Kind: GoalSurvive, Target: 60}, false},
+		{ObjectiveDef{Kind: LimitTime}, false},
+		{ObjectiveDef{Kind: LimitDeaths}, true}, // Nobody may die.
+		{ObjectiveDef{Kind: LimitRating, Target: -1}, false},
+		{ObjectiveDef{Kind: "high_score", Target: 1}, false},
+	} {
+		if err := vt.def.Validate(); (err == nil) != vt.ok {
+			t.Errorf("Expected %+v to be valid %v, got %v", vt.def, vt.ok, err)
+		}
+	}
+	for _, def := range DefaultObjectives {
+		if err := def.Validate(); err != nil {
+			t.Errorf("Expected default objectives to be valid, got %v", err)
+		}
+	}
+}
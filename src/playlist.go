@@ -0,0 +1,58 @@
+// Copyright 2015 Pikkpoiss
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+)
+
+const (
+	DefaultPlaylistLevel = "default"
+	MoodCalm             = "calm"
+	MoodTense            = "tense"
+)
+
+// Playlist lists the music files to play for each level and mood, relative
+// to the music directory.
+type Playlist struct {
+	Levels map[string]map[string][]string
+}
+
+func LoadPlaylist(path string) (playlist *Playlist, err error) {
+	var data []byte
+	if data, err = ioutil.ReadFile(path); err != nil {
+		return
+	}
+	playlist = &Playlist{}
+	err = json.Unmarshal(data, playlist)
+	return
+}
+
+// Tracks returns the files for level and mood, falling back to the default
+// level and then to the calm mood.
+func (p *Playlist) Tracks(level, mood string) []string {
+	for _, l := range []string{level, DefaultPlaylistLevel} {
+		if moods, ok := p.Levels[l]; ok {
+			if tracks, ok := moods[mood]; ok && len(tracks) > 0 {
+				return tracks
+			}
+			if tracks, ok := moods[MoodCalm]; ok && len(tracks) > 0 {
+				return tracks
+			}
+		}
+	}
+	return nil
+}
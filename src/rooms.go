@@ -0,0 +1,169 @@
+// Copyright 2015 Pikkpoiss
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	ThemeBonus    = 0.25 // Extra fear for each matching block after the first.
+	MaxThemeBonus = 1.0
+)
+
+// Room is a part of the map which is either named by the map's regions or
+// enclosed by walls.
+type Room struct {
+	Name      string
+	Theme     string // Set by the map, otherwise the most common block theme.
+	Occupancy int
+	Blocks    []BlockPlacement
+	Matching  int // Number of blocks matching the room's theme.
+	Size      int
+	mapTheme  string
+}
+
+// FearMultiplier returns how much scarier blocks in this room are thanks to
+// their matching theme.
+func (r *Room) FearMultiplier() float64 {
+	if r.Matching < 2 {
+		return 1.0
+	}
+	return 1.0 + math.Min(float64(r.Matching-1)*ThemeBonus, MaxThemeBonus)
+}
+
+// updateTheme picks the room's theme and counts the blocks matching it.
+func (r *Room) updateTheme() {
+	var counts = map[string]int{}
+	for _, placement := range r.Blocks {
+		if placement.Block.Theme != "" {
+			counts[placement.Block.Theme]++
+		}
+	}
+	r.Theme = r.mapTheme
+	if r.Theme == "" {
+		for theme, count := range counts {
+			if count > counts[r.Theme] || (count == counts[r.Theme] && theme < r.Theme) {
+				r.Theme = theme
+			}
+		}
+	}
+	r.Matching = counts[r.Theme]
+}
+
+type Rooms struct {
+	rooms []*Room
+	cells map[Ivec2]*Room
+}
+
+// NewRooms divides the passable background of the grid into rooms. Tiles
+// with a region property belong to the room of that name, while any other
+// tiles are flood filled into rooms bounded by walls and regions.
+func NewRooms(grid *Grid) *Rooms {
+	var (
+		r = &Rooms{
+			cells: map[Ivec2]*Room{},
+		}
+		named = map[string]*Room{}
+		item  *GridItem
+		pt    Ivec2
+		x     int32
+		y     int32
+	)
+	for y = 0; y < grid.Height(); y++ {
+		for x = 0; x < grid.Width(); x++ {
+			pt = Ivec2{x, y}
+			if item = grid.GetBg(pt); item == nil || !item.Passable() || item.Region() == "" {
+				continue
+			}
+			room, ok := named[item.Region()]
+			if !ok {
+				room = &Room{Name: item.Region()}
+				named[item.Region()] = room
+				r.rooms = append(r.rooms, room)
+			}
+			r.add(room, item, pt)
+		}
+	}
+	for y = 0; y < grid.Height(); y++ {
+		for x = 0; x < grid.Width(); x++ {
+			pt = Ivec2{x, y}
+			if _, ok := r.cells[pt]; ok || !r.fillable(grid, pt) {
+				continue
+			}
+			room := &Room{Name: fmt.Sprintf("Room %v", len(r.rooms)+1)}
+			r.rooms = append(r.rooms, room)
+			r.fill(grid, room, pt)
+		}
+	}
+	return r
+}
+
+func (r *Rooms) add(room *Room, item *GridItem, pt Ivec2) {
+	r.cells[pt] = room
+	room.Size++
+	if room.mapTheme == "" {
+		room.mapTheme = item.Theme()
+	}
+}
+
+func (r *Rooms) fillable(grid *Grid, pt Ivec2) bool {
+	item := grid.GetBg(pt)
+	return item != nil && item.Passable() && item.Region() == ""
+}
+
+func (r *Rooms) fill(grid *Grid, room *Room, start Ivec2) {
+	var queue = []Ivec2{start}
+	r.add(room, grid.GetBg(start), start)
+	for len(queue) > 0 {
+		pt := queue[0]
+		queue = queue[1:]
+		for _, adj := range grid.getAdjacent(pt) {
+			if _, ok := r.cells[adj]; ok || !r.fillable(grid, adj) {
+				continue
+			}
+			r.add(room, grid.GetBg(adj), adj)
+			queue = append(queue, adj)
+		}
+	}
+}
+
+// At returns the room containing pt, or nil if there is none.
+func (r *Rooms) At(pt Ivec2) *Room {
+	return r.cells[pt]
+}
+
+// SetBlocks assigns every placement to the room containing its origin and
+// updates the rooms' themes.
+func (r *Rooms) SetBlocks(blocks map[Ivec2]BlockPlacement) {
+	for _, room := range r.rooms {
+		room.Blocks = room.Blocks[0:0]
+	}
+	for _, placement := range blocks {
+		if room := r.At(placement.Pos); room != nil {
+			room.Blocks = append(room.Blocks, placement)
+		}
+	}
+	for _, room := range r.rooms {
+		room.updateTheme()
+	}
+}
+
+func (r *Rooms) ClearOccupancy() {
+	for _, room := range r.rooms {
+		room.Occupancy = 0
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright 2015 Pikkpoiss
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+)
+
+func TestRoomsSplitByWalls(t *testing.T) {
+	var (
+		g      = newTestGrid(5, 3)
+		region = NewGridItem(true, false, "", nil)
+	)
+	for y := int32(0); y < 3; y++ {
+		g.background.Set(2, y, NewGridItem(false, true, "", nil))
+	}
+	region.region = "Crypt"
+	region.theme = "crypt"
+	g.background.Set(4, 2, region)
+	rooms := NewRooms(g)
+	if left, right := rooms.At(Ivec2{0, 0}), rooms.At(Ivec2{3, 0}); left == nil || left == right {
+		t.Fatalf("Expected wall to separate rooms, got %v and %v", left, right)
+	}
+	if room := rooms.At(Ivec2{2, 1}); room != nil {
+		t.Errorf("Expected walls to belong to no room, got %v", room.Name)
+	}
+	if room := rooms.At(Ivec2{4, 2}); room == nil || room.Name != "Crypt" || room.Size != 1 {
+		t.Fatalf("Expected region to form its own room, got %v", room)
+	}
+	if size := rooms.At(Ivec2{3, 0}).Size; size != 5 {
+		t.Errorf("Expected 5 tiles right of the wall, got %v", size)
+	}
+}
+
+func TestRoomThemes(t *testing.T) {
+	var (
+		g      = newTestGrid(5, 5)
+		rooms  = NewRooms(g)
+		blocks = map[Ivec2]BlockPlacement{}
+	)
+	for i, block := range []*Block{&SkellyBlock, &SkellyBlock, &SkellyBlock, &SpikesBlock} {
+		pt := Ivec2{int32(i), 0}
+		blocks[pt] = BlockPlacement{Pos: pt, Block: block}
+	}
+	rooms.SetBlocks(blocks)
+	room := rooms.At(Ivec2{0, 0})
+	if room.Theme != SkellyBlock.Theme || room.Matching != 3 {
+		t.Fatalf("Expected theme %v with 3 matches, got %v with %v", SkellyBlock.Theme, room.Theme, room.Matching)
+	}
+	if mult := room.FearMultiplier(); mult != 1.5 {
+		t.Errorf("Expected fear multiplier 1.5 got %v", mult)
+	}
+}
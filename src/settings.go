@@ -0,0 +1,86 @@
+// Copyright 2015 Pikkpoiss
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"math"
+)
+
+const SettingsFile = "settings.json"
+
+// Profiles are the players who can keep their own medals on one machine.
+var Profiles = []string{"Player 1", "Player 2", "Player 3"}
+
+type Settings struct {
+	MasterVolume float64
+	MusicVolume  float64
+	EffectVolume float64
+	MusicMuted   bool
+	Profile      string // Whose medals are awarded.
+	path         string
+}
+
+func NewSettings() *Settings {
+	return &Settings{
+		MasterVolume: 1.0,
+		MusicVolume:  0.8,
+		EffectVolume: 1.0,
+		MusicMuted:   false,
+		Profile:      Profiles[0],
+	}
+}
+
+// LoadSettings returns the settings saved at path, or the defaults if
+// nothing has been saved yet or they can't be read.
+func LoadSettings(path string) (s *Settings, err error) {
+	s = NewSettings()
+	s.path = path
+	if err = LoadJSON(path, s); err != nil {
+		s = NewSettings()
+		s.path = path
+		return
+	}
+	s.MasterVolume = clampVolume(s.MasterVolume)
+	s.MusicVolume = clampVolume(s.MusicVolume)
+	s.EffectVolume = clampVolume(s.EffectVolume)
+	if s.Profile == "" {
+		s.Profile = Profiles[0]
+	}
+	return
+}
+
+func (s *Settings) Save() error {
+	if s.path == "" {
+		return nil
+	}
+	return SaveJSON(s.path, s)
+}
+
+// MusicLevel is the effective volume of the music bus.
+func (s *Settings) MusicLevel() float64 {
+	if s.MusicMuted {
+		return 0
+	}
+	return s.MasterVolume * s.MusicVolume
+}
+
+// EffectLevel is the effective volume of the sound effects bus.
+func (s *Settings) EffectLevel() float64 {
+	return s.MasterVolume * s.EffectVolume
+}
+
+func clampVolume(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
@@ -0,0 +1,89 @@
+// Copyright 2015 Pikkpoiss
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"../lib/twodee"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+)
+
+// SoundGroup reserves a number of mixer channels for a class of sounds so
+// that, say, a crowd of traps can't drown out a death.
+type SoundGroup struct {
+	Channels int
+}
+
+// SoundEntry maps an event to the files which may be played for it.
+type SoundEntry struct {
+	Event       string   // Name of the event type, see GameEventNames.
+	Block       string   // Only play for events caused by a block with this title.
+	Files       []string // One of these is picked at random, relative to MusicDir.
+	Cooldown    float64  // Seconds before this sound may be played again.
+	PerInstance bool     // Cooldown is tracked per block rather than globally.
+	Group       string
+	Priority    int
+	Volume      float64 // Defaults to 1.
+	eventType   twodee.GameEventType
+}
+
+type SoundCatalog struct {
+	Groups map[string]SoundGroup
+	Sounds []*SoundEntry
+}
+
+func LoadSoundCatalog(path string) (catalog *SoundCatalog, err error) {
+	var (
+		data []byte
+		ok   bool
+	)
+	if data, err = ioutil.ReadFile(path); err != nil {
+		return
+	}
+	catalog = &SoundCatalog{}
+	if err = json.Unmarshal(data, catalog); err != nil {
+		return
+	}
+	for _, entry := range catalog.Sounds {
+		if entry.eventType, ok = GameEventNames[entry.Event]; !ok {
+			err = fmt.Errorf("Unknown event %v in %v", entry.Event, path)
+			return
+		}
+		if _, ok = catalog.Groups[entry.Group]; !ok {
+			err = fmt.Errorf("Unknown sound group %v in %v", entry.Group, path)
+			return
+		}
+		if len(entry.Files) == 0 {
+			err = fmt.Errorf("No files for %v sound in %v", entry.Event, path)
+			return
+		}
+		if entry.Volume == 0 {
+			entry.Volume = 1
+		}
+	}
+	return
+}
+
+// Matches returns whether the entry should play for e.
+func (s *SoundEntry) Matches(e twodee.GETyper) bool {
+	if s.Block == "" {
+		return true
+	}
+	if sourced, ok := e.(BlockSourced); ok {
+		return sourced.SourceBlock().Block.Title == s.Block
+	}
+	return false
+}
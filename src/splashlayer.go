@@ -42,7 +42,7 @@ func NewSplashLayer(state *State, app *Application, grid *Grid) (layer *SplashLa
 		camera *twodee.Camera
 	)
 	if camera, err = twodee.NewCamera(
-		twodee.Rect(0, 0, float32(grid.Width()), float32(grid.Height())),
+		twodee.Rect(0, 0, float32(grid.ViewWidth()), float32(grid.ViewHeight())),
 		twodee.Rect(0, 0, ScreenWidth, ScreenHeight),
 	); err != nil {
 		return
@@ -80,15 +80,10 @@ func (l *SplashLayer) AdvanceState() {
 
 func (l *SplashLayer) HandleEvent(evt twodee.Event) bool {
 	if l.state.SplashState != SplashDisabled {
+		if l.app.Input.Action(evt) == ActionAdvance {
+			l.AdvanceState()
+		}
 		switch event := evt.(type) {
-		case *twodee.KeyEvent:
-			if event.Type != twodee.Press {
-				break
-			}
-			switch event.Code {
-			case twodee.KeySpace:
-				l.AdvanceState()
-			}
 		case *twodee.MouseButtonEvent:
 			if event.Type == twodee.Press && event.Button == twodee.MouseButtonLeft {
 				l.AdvanceState()
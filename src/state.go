@@ -22,10 +22,14 @@ type State struct {
 	Exit        bool
 	Geld        int
 	Rating      int
+	Handymen    int
+	Minions     int
+	Level       int // Index into the level catalog, kept across resets.
 	Debug       bool
 	MousePos    mgl32.Vec2
 	MouseCursor string
 	SplashState SplashState
+	Result      *LevelResult // Last level won, for the win screen.
 }
 
 func NewState() *State {
@@ -47,8 +51,11 @@ func (s *State) Reset() {
 	s.Exit = false
 	s.Geld = 100
 	s.Rating = 5
+	s.Handymen = 0
+	s.Minions = 0
 	s.Debug = false
 	s.MousePos = mgl32.Vec2{0, 0}
 	s.MouseCursor = "mouse_00"
 	s.SplashState = SplashStart
+	s.Result = nil
 }
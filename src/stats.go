@@ -0,0 +1,137 @@
+// Copyright 2015 Pikkpoiss
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"../lib/twodee"
+	"time"
+)
+
+// Visitors whose review of their visit is at least this scary count as
+// thrilled.
+const ThrillFear = 9.0
+
+// Stats holds running totals for a single play through a level.
+type Stats struct {
+	Spawned       int
+	Exited        int
+	Thrilled      int
+	Died          int
+	Scares        int
+	BlocksPlaced  int
+	BlocksRemoved int
+	GeldEarned    int
+	GeldSpent     int
+	PeakRating    int
+	TotalExitFear float64
+	PlayTime      time.Duration // Filled in by Level.Stats.
+}
+
+// AverageFear returns the mean review of every mob which made it to the exit,
+// the same fear the rating is worked out from.
+func (s Stats) AverageFear() float64 {
+	if s.Exited == 0 {
+		return 0
+	}
+	return s.TotalExitFear / float64(s.Exited)
+}
+
+// StatsTracker keeps Stats up to date by observing gameplay events.
+type StatsTracker struct {
+	Stats
+	OnChange func(s *Stats) // Optional, called after every update.
+	subs     *Subscriptions
+}
+
+func NewStatsTracker(handler *twodee.GameEventHandler) *StatsTracker {
+	t := &StatsTracker{
+		subs: NewSubscriptions(handler),
+	}
+	t.subs.Add(MobSpawned, t.onMobSpawned)
+	t.subs.Add(MobScared, t.onMobScared)
+	t.subs.Add(MobDied, t.onMobDied)
+	t.subs.Add(MobExited, t.onMobExited)
+	t.subs.Add(BlockPlaced, t.onBlockPlaced)
+	t.subs.Add(BlockRemoved, t.onBlockRemoved)
+	t.subs.Add(GeldChanged, t.onGeldChanged)
+	t.subs.Add(RatingChanged, t.onRatingChanged)
+	return t
+}
+
+func (t *StatsTracker) Delete() {
+	t.subs.Delete()
+}
+
+func (t *StatsTracker) changed() {
+	if t.OnChange != nil {
+		t.OnChange(&t.Stats)
+	}
+}
+
+func (t *StatsTracker) onMobSpawned(e twodee.GETyper) {
+	t.Spawned++
+	t.changed()
+}
+
+func (t *StatsTracker) onMobScared(e twodee.GETyper) {
+	if evt, ok := e.(*ScareEvent); ok {
+		t.Scares += len(evt.MobIds)
+	}
+	t.changed()
+}
+
+func (t *StatsTracker) onMobDied(e twodee.GETyper) {
+	t.Died++
+	t.changed()
+}
+
+func (t *StatsTracker) onMobExited(e twodee.GETyper) {
+	if evt, ok := e.(*MobEvent); ok {
+		t.Exited++
+		t.TotalExitFear += evt.Review
+		if evt.Review >= ThrillFear {
+			t.Thrilled++
+		}
+	}
+	t.changed()
+}
+
+func (t *StatsTracker) onBlockPlaced(e twodee.GETyper) {
+	t.BlocksPlaced++
+	t.changed()
+}
+
+func (t *StatsTracker) onBlockRemoved(e twodee.GETyper) {
+	t.BlocksRemoved++
+	t.changed()
+}
+
+func (t *StatsTracker) onGeldChanged(e twodee.GETyper) {
+	if evt, ok := e.(*AmountEvent); ok {
+		if evt.Delta > 0 {
+			t.GeldEarned += evt.Delta
+		} else {
+			t.GeldSpent -= evt.Delta
+		}
+	}
+	t.changed()
+}
+
+func (t *StatsTracker) onRatingChanged(e twodee.GETyper) {
+	if evt, ok := e.(*AmountEvent); ok && evt.Value > t.PeakRating {
+		t.PeakRating = evt.Value
+	}
+	t.changed()
+}
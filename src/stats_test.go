@@ -0,0 +1,37 @@
+// Copyright 2015 Pikkpoiss
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"../lib/twodee"
+	"testing"
+)
+
+func TestExitsCountReviews(t *testing.T) {
+	var tracker = NewStatsTracker(twodee.NewGameEventHandler(NumGameEventTypes))
+	for _, review := range []float64{ThrillFear + 1, ThrillFear - 1} {
+		mob := newTestMob()
+		mob.Fear = 10 // Fear at the door doesn't count, only the review.
+		evt := NewMobEvent(MobExited, mob)
+		evt.Review = review
+		tracker.onMobExited(evt)
+	}
+	if tracker.Exited != 2 || tracker.Thrilled != 1 {
+		t.Errorf("Expected 2 visitors to leave and 1 to be thrilled, got %v and %v", tracker.Exited, tracker.Thrilled)
+	}
+	if fear := tracker.AverageFear(); fear != ThrillFear {
+		t.Errorf("Expected the average review %v, got %v", ThrillFear, fear)
+	}
+}
@@ -16,6 +16,7 @@ package main
 
 import (
 	"../lib/twodee"
+	"github.com/go-gl/mathgl/mgl32"
 )
 
 type UiState interface {
@@ -31,23 +32,58 @@ func (s BaseUiState) HandleEvent(level *Level, evt twodee.Event) UiState {
 	switch event := evt.(type) {
 	case *twodee.MouseMoveEvent:
 		level.SetMouse(event.X, event.Y)
-	case *twodee.KeyEvent:
-		if event.Type == twodee.Press {
-			code := int(event.Code - twodee.Key1)
-			if code >= 0 && code < 9 && code < len(HudBlocks) - 1 {
-				return NewBlockUiState(HudBlocks[code + 1]) // Skip first (delete)
+	}
+	if action, down := level.App.Input.Held(evt); action != ActionNone {
+		if dir, ok := avatarDirections[action]; ok {
+			level.Avatar.Steer(dir, down)
+		}
+	}
+	switch action := level.App.Input.Action(evt); action {
+	case ActionNone:
+	case ActionNormalMode:
+		return NewNormalUiState()
+	case ActionToggleDoor:
+		level.ToggleDoor(level.GetMouse())
+	case ActionRepair:
+		level.RepairBlock(level.GetMouse())
+	case ActionHireHandyman:
+		level.HireHandyman()
+	case ActionHireMinion:
+		return NewPatrolUiState()
+	case ActionFloorUp:
+		level.SetFloor(level.Floor + 1)
+	case ActionFloorDown:
+		level.SetFloor(level.Floor - 1)
+	default:
+		for _, block := range HudBlocks {
+			if block.Action == action {
+				return NewToolbarUiState(block)
 			}
-			switch event.Code {
-			case twodee.Key0:
-				return NewNormalUiState()
-			case twodee.KeyD:
-				return NewDeleteUiState()
+		}
+		for _, ability := range Abilities {
+			if ability.Action == action {
+				level.UseAbility(ability)
 			}
 		}
 	}
 	return nil
 }
 
+var avatarDirections = map[InputAction]mgl32.Vec2{
+	ActionAvatarUp:    mgl32.Vec2{0, 1},
+	ActionAvatarDown:  mgl32.Vec2{0, -1},
+	ActionAvatarLeft:  mgl32.Vec2{-1, 0},
+	ActionAvatarRight: mgl32.Vec2{1, 0},
+}
+
+// NewToolbarUiState returns the state for using block from the toolbar.
+func NewToolbarUiState(block *Block) UiState {
+	if block == &DeleteBlock {
+		return NewDeleteUiState()
+	}
+	return NewBlockUiState(block)
+}
+
 type NormalUiState struct {
 	BaseUiState
 }
@@ -70,10 +106,13 @@ func (s *NormalUiState) HandleEvent(level *Level, evt twodee.Event) UiState {
 	switch event := evt.(type) {
 	case *twodee.MouseButtonEvent:
 		if event.Type == twodee.Press && event.Button == twodee.MouseButtonLeft {
-			if level.State.Debug {
+			if !level.ToggleDoor(level.GetMouse()) && level.State.Debug {
 				level.AddMob(level.GetMouse())
 			}
 		}
+		if event.Type == twodee.Press && event.Button == twodee.MouseButtonRight {
+			level.Avatar.MoveTo(level.GetMouse())
+		}
 	}
 	return nil
 }
@@ -140,20 +179,60 @@ func (s *BlockUiState) HandleEvent(level *Level, evt twodee.Event) UiState {
 		level.SetHighlights(level.GetMouse(), s.target, s.variant)
 	case *twodee.MouseButtonEvent:
 		if event.Type == twodee.Press && event.Button == twodee.MouseButtonLeft {
-			if s.target.Cost <= level.State.Geld {
-				level.State.Geld = level.State.Geld - s.target.Cost
-				level.SetBlock(level.GetMouse(), s.target, s.variant)
-				level.gameEventHandler.Enqueue(twodee.NewBasicGameEvent(PlayPlaceBlockEffect))
-			}
+			level.SetBlock(level.GetMouse(), s.target, s.variant)
 		}
-	case *twodee.KeyEvent:
-		if event.Type == twodee.Press {
-			switch event.Code {
-			case twodee.KeyR:
-				s.variant = (s.variant + 1) % len(s.target.Variants)
-				level.SetHighlights(level.GetMouse(), s.target, s.variant)
+	}
+	switch level.App.Input.Action(evt) {
+	case ActionRotate:
+		s.variant = (s.variant + 1) % len(s.target.Variants)
+		level.SetHighlights(level.GetMouse(), s.target, s.variant)
+	}
+	return nil
+}
+
+// PatrolUiState lets the player click out the route of a new minion. Right
+// click or MenuSelect hires the minion.
+type PatrolUiState struct {
+	BaseUiState
+	route []Ivec2
+}
+
+func NewPatrolUiState() UiState {
+	return &PatrolUiState{}
+}
+
+func (s *PatrolUiState) Register(level *Level) {
+	level.SetCursor("mouse_01")
+}
+
+func (s *PatrolUiState) Unregister(level *Level) {
+	level.UnsetHighlights()
+}
+
+func (s *PatrolUiState) HandleEvent(level *Level, evt twodee.Event) UiState {
+	if state := s.BaseUiState.HandleEvent(level, evt); state != nil {
+		return state
+	}
+	switch event := evt.(type) {
+	case *twodee.MouseButtonEvent:
+		if event.Type != twodee.Press {
+			break
+		}
+		switch event.Button {
+		case twodee.MouseButtonLeft:
+			if pt, ok := level.PatrolPoint(level.GetMouse()); ok && len(s.route) < MaxPatrolPoints {
+				s.route = append(s.route, pt)
+				level.SetPatrolHighlights(s.route)
 			}
+		case twodee.MouseButtonRight:
+			level.HireMinion(s.route)
+			return NewNormalUiState()
 		}
 	}
+	switch level.App.Input.Action(evt) {
+	case ActionMenuSelect:
+		level.HireMinion(s.route)
+		return NewNormalUiState()
+	}
 	return nil
 }
@@ -69,3 +69,20 @@ func minInt(a, b int) int {
 	}
 	return b
 }
+
+func absInt32(a int32) int32 {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
+
+func signInt32(a int32) int32 {
+	switch {
+	case a < 0:
+		return -1
+	case a > 0:
+		return 1
+	}
+	return 0
+}
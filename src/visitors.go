@@ -0,0 +1,112 @@
+// Copyright 2015 Pikkpoiss
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"math/rand"
+	"time"
+)
+
+// MobKind describes a type of visitor. Most visitors just want to be scared,
+// but some of them go after the blocks.
+type MobKind struct {
+	Name          string
+	Chance        float64 // Relative chance of a new visitor being of this kind.
+	Temperament   *Temperament
+	Sprite        string  // Spritesheet frame prefix, e.g. "human01".
+	Scale         float32 // Sprite size, so special visitors stand out.
+	SabotageRange float32 // Distance from which the visitor can get at blocks.
+	DamagePerSec  float64 // Health taken from blocks in range per second.
+	DisableFor    time.Duration
+	Disarms       bool // Breaks blocks outright, they need fixing or replacing.
+}
+
+// Sabotages returns true if visitors of this kind do anything to blocks.
+func (k *MobKind) Sabotages() bool {
+	return k.DamagePerSec > 0 || k.DisableFor > 0 || k.Disarms
+}
+
+var (
+	HardyTemperament = Temperament{
+		DecayPerSec:   0.5,
+		DecayDelay:    time.Second,
+		ToleranceGain: 0.1,
+		MaxTolerance:  0.8,
+		Habituation:   0.5,
+		PeakWeight:    0.5,
+		SampleEvery:   time.Second,
+	}
+
+	VisitorKind = MobKind{
+		Name:        "Visitor",
+		Chance:      0.9,
+		Temperament: &DefaultTemperament,
+		Sprite:      "human01",
+		Scale:       1.0,
+	}
+
+	GhostHunterKind = MobKind{
+		Name:          "Ghost Hunter",
+		Chance:        0.04,
+		Temperament:   &HardyTemperament,
+		Sprite:        "human02",
+		Scale:         1.15,
+		SabotageRange: 1.5,
+		DamagePerSec:  2.0,
+	}
+
+	SkepticKind = MobKind{
+		Name:          "Skeptic",
+		Chance:        0.04,
+		Temperament:   &HardyTemperament,
+		Sprite:        "human03",
+		Scale:         0.9,
+		SabotageRange: 2.0,
+		DisableFor:    5 * time.Second,
+	}
+
+	MaintenanceKind = MobKind{
+		Name:          "Maintenance Crew",
+		Chance:        0.02,
+		Temperament:   &DefaultTemperament,
+		Sprite:        "human04",
+		Scale:         1.1,
+		SabotageRange: 1.0,
+		Disarms:       true,
+	}
+
+	MobKinds = []*MobKind{
+		&VisitorKind,
+		&GhostHunterKind,
+		&SkepticKind,
+		&MaintenanceKind,
+	}
+)
+
+// PickMobKind returns a kind of visitor at random, weighted by Chance.
+func PickMobKind() *MobKind {
+	var total float64
+	for _, kind := range MobKinds {
+		total += kind.Chance
+	}
+	pick := rand.Float64() * total
+	for _, kind := range MobKinds {
+		if pick < kind.Chance {
+			return kind
+		}
+		pick -= kind.Chance
+	}
+	return &VisitorKind
+}
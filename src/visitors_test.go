@@ -0,0 +1,106 @@
+// Copyright 2015 Pikkpoiss
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestPickMobKind(t *testing.T) {
+	var (
+		counts = map[*MobKind]int{}
+		total  float64
+		picks  = 20000
+	)
+	rand.Seed(1)
+	for i := 0; i < picks; i++ {
+		counts[PickMobKind()]++
+	}
+	for _, kind := range MobKinds {
+		total += kind.Chance
+	}
+	for _, kind := range MobKinds {
+		var (
+			expected = kind.Chance / total
+			got      = float64(counts[kind]) / float64(picks)
+		)
+		if math.Abs(got-expected) > 0.01 {
+			t.Errorf("Expected %v to be picked %.3f of the time, got %.3f", kind.Name, expected, got)
+		}
+	}
+}
+
+// addTestBlock places a fresh copy of block at pt on level.
+func addTestBlock(t *testing.T, level *Level, block *Block, pt Ivec2) Ivec2 {
+	var placement = BlockPlacement{Pos: pt, Block: block, Health: block.Health}
+	center, ok := level.Grid.SetBlock(placement)
+	if !ok {
+		t.Fatalf("Could not place %v at %v", block.Title, pt)
+	}
+	level.blocks[center] = placement
+	return center
+}
+
+func TestUpdateSabotage(t *testing.T) {
+	for _, st := range []struct {
+		kind     *MobKind
+		health   float64
+		disabled time.Duration
+		active   bool
+	}{
+		{&VisitorKind, SkellyBlock.Health, 0, true},
+		{&GhostHunterKind, SkellyBlock.Health - GhostHunterKind.DamagePerSec, 0, true},
+		{&SkepticKind, SkellyBlock.Health, SkepticKind.DisableFor, false},
+		{&MaintenanceKind, 0, 0, false},
+	} {
+		var (
+			level = newTestLevel(newTestGrid(8, 8))
+			key   = addTestBlock(t, level, &SkellyBlock, Ivec2{4, 4})
+		)
+		addTestMobs(level, CalmFear, mgl32.Vec2{3.5, 4.5})
+		level.Mobs[0].Kind = st.kind
+		level.updateSabotage(time.Second)
+		placement := level.blocks[key]
+		if placement.Health != st.health {
+			t.Errorf("Expected %v to leave the block with %v health, got %v", st.kind.Name, st.health, placement.Health)
+		}
+		if placement.DisabledFor != st.disabled {
+			t.Errorf("Expected %v to disable the block for %v, got %v", st.kind.Name, st.disabled, placement.DisabledFor)
+		}
+		if placement.Active() != st.active {
+			t.Errorf("Expected the block to be active %v after %v", st.active, st.kind.Name)
+		}
+		if state := level.Grid.Get(key).State(); !st.active && state != BlockDisabled {
+			t.Errorf("Expected %v to show the block disabled, got state %v", st.kind.Name, state)
+		}
+	}
+}
+
+func TestSabotageNeedsRange(t *testing.T) {
+	var (
+		level = newTestLevel(newTestGrid(12, 8))
+		key   = addTestBlock(t, level, &SkellyBlock, Ivec2{2, 4})
+	)
+	addTestMobs(level, CalmFear, mgl32.Vec2{2.5 + MaintenanceKind.SabotageRange + 1, 4.5})
+	level.Mobs[0].Kind = &MaintenanceKind
+	level.updateSabotage(time.Second)
+	if placement := level.blocks[key]; !placement.Active() {
+		t.Errorf("Expected blocks out of range to be left alone")
+	}
+}